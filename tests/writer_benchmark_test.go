@@ -533,3 +533,92 @@ func TestDwriterManyFiles(t *testing.T) {
 		})
 	}
 }
+
+func BenchmarkWorkerCap(b *testing.B) {
+	writer.SetDebugMode(false)
+
+	const fileCount = 5000
+	cases := []struct {
+		name   string
+		factor int
+	}{
+		{"Uncapped5000Workers", 0},
+		{"CappedDefault", 4},
+	}
+
+	for _, c := range cases {
+		b.Run(c.name, func(b *testing.B) {
+			myWriter, myFiles := setupWriter(fileCount)
+			defer cleanupFiles(myFiles)
+
+			if err := myWriter.SetRetries(0); err != nil {
+				b.Fatalf("Error setting retries: %v", err)
+			}
+			if err := myWriter.SetMaxPool(fileCount); err != nil {
+				b.Fatalf("Error setting max pool: %v", err)
+			}
+			if err := myWriter.SetWorkerCapFactor(c.factor); err != nil {
+				b.Fatalf("Error setting worker cap factor: %v", err)
+			}
+
+			workers := fileCount
+			if c.factor > 0 {
+				workers = runtime.GOMAXPROCS(0) * c.factor
+			}
+
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				result, err := myWriter.Write(fileCount)
+				if err != nil {
+					b.Errorf("Write returned error: %v", err)
+					continue
+				}
+				if result.Failure != 0 {
+					b.Errorf("Expected 0 failures, got %d", result.Failure)
+				}
+			}
+			b.StopTimer()
+			b.ReportMetric(float64(workers), "workers")
+
+			err := myWriter.CloseAllConns()
+			if err != nil {
+				b.Errorf("Error closing connections: %v", err)
+			}
+		})
+	}
+}
+
+func BenchmarkRecordAssembly(b *testing.B) {
+	writer.SetDebugMode(false)
+
+	myWriter, myFiles := setupWriter(100)
+	defer cleanupFiles(myFiles)
+
+	myWriter.SetChecksumFooter(true)
+	if err := myWriter.SetNewlineStyle(writer.NewlineCRLF); err != nil {
+		b.Fatalf("Error setting newline style: %v", err)
+	}
+	if err := myWriter.SetMaxPool(100); err != nil {
+		b.Fatalf("Error setting max pool: %v", err)
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		result, err := myWriter.Write(runtime.NumCPU())
+		if err != nil {
+			b.Errorf("Write returned error: %v", err)
+			continue
+		}
+		if result.Failure != 0 {
+			b.Errorf("Expected 0 failures, got %d", result.Failure)
+		}
+	}
+	b.StopTimer()
+
+	err := myWriter.CloseAllConns()
+	if err != nil {
+		b.Errorf("Error closing connections: %v", err)
+	}
+}
@@ -1,10 +1,20 @@
 package tests
 
 import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
 	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
 	writer "github.com/JuniorVieira99/jr_writer"
+	"io"
 	"os"
+	"path/filepath"
+	"slices"
 	"strings"
+	"sync"
 	"testing"
 	"time"
 )
@@ -463,3 +473,2526 @@ func TestResultPrint(t *testing.T) {
 	}
 	results.GetStringRepresentation()
 }
+
+// Test Writer created with a pointer to a nil files slice
+func TestNewWriterNilFilesSlice(t *testing.T) {
+	var nilFiles []*os.File
+	myWriter := writer.NewWriter(&nilFiles, modeA, &message, 10, 3, 100)
+
+	if myWriter.GetFiles() == nil || *myWriter.GetFiles() == nil {
+		t.Fatal("Expected files to be normalized to an empty slice")
+	}
+
+	_, err := myWriter.Write(2)
+	if err == nil {
+		t.Error("Expected error for empty files, got nil")
+	} else if !strings.Contains(err.Error(), "files is empty") {
+		t.Errorf("Expected 'files is empty' error, got: %v", err)
+	}
+
+	newFiles := makeFiles(1)
+	defer cleanupFiles(newFiles)
+
+	err = myWriter.AddFiles(newFiles)
+	if err != nil {
+		t.Errorf("AddFiles returned error: %v", err)
+	}
+	if len(*myWriter.GetFiles()) != 1 {
+		t.Errorf("Expected 1 file after AddFiles, got %d", len(*myWriter.GetFiles()))
+	}
+
+	// SetFiles with a pointer to a nil slice behaves like an empty slice
+	err = myWriter.SetFiles(&nilFiles)
+	if err != nil {
+		t.Errorf("SetFiles returned error: %v", err)
+	}
+	if _, err = myWriter.Write(2); err == nil {
+		t.Error("Expected error for empty files after SetFiles, got nil")
+	}
+
+	err = myWriter.CloseAllConns()
+	if err != nil {
+		t.Errorf("CloseAllConns returned error: %v", err)
+	}
+}
+
+// Test verifying written content across all files
+func TestVerifyAll(t *testing.T) {
+	myFiles := makeFiles(3)
+	defer cleanupFiles(myFiles)
+
+	myWriter := writer.NewWriter(&myFiles, modeA, &message, 10, 3, 100)
+
+	results, err := myWriter.Write(2)
+	if err != nil {
+		t.Fatalf("Write returned error: %v", err)
+	}
+	if results.Success != 3 {
+		t.Errorf("Expected 3 successful writes, got %d", results.Success)
+	}
+
+	verified, err := myWriter.VerifyAll()
+	if err != nil {
+		t.Fatalf("VerifyAll returned error: %v", err)
+	}
+	if len(verified) != 3 {
+		t.Errorf("Expected 3 verified files, got %d", len(verified))
+	}
+	for name, ok := range verified {
+		if !ok {
+			t.Errorf("Expected file %s to verify, got false", name)
+		}
+	}
+
+	// Write mode requires an exact match
+	modeW, _ := writer.NewMode(&appendModeW)
+	err = myWriter.SetMode(modeW)
+	if err != nil {
+		t.Errorf("SetMode returned error: %v", err)
+	}
+	verified, err = myWriter.VerifyAll()
+	if err != nil {
+		t.Fatalf("VerifyAll returned error: %v", err)
+	}
+	for name, ok := range verified {
+		if !ok {
+			t.Errorf("Expected file %s to verify in 'w' mode, got false", name)
+		}
+	}
+
+	err = myWriter.CloseAllConns()
+	if err != nil {
+		t.Errorf("CloseAllConns returned error: %v", err)
+	}
+}
+
+// Test writing logfmt lines
+func TestWriteLogfmt(t *testing.T) {
+	myFiles := makeFiles(2)
+	defer cleanupFiles(myFiles)
+
+	myWriter := writer.NewWriter(&myFiles, modeA, &message, 10, 3, 100)
+
+	fields := map[string]string{
+		"level": "info",
+		"msg":   "user logged in",
+		"user":  "jr",
+	}
+	results, err := myWriter.WriteLogfmt(fields, 2)
+	if err != nil {
+		t.Fatalf("WriteLogfmt returned error: %v", err)
+	}
+	if results.Success != 2 {
+		t.Errorf("Expected 2 successful writes, got %d", results.Success)
+	}
+
+	expected := "level=info msg=\"user logged in\" user=jr\n"
+	for _, file := range myFiles {
+		content, err := os.ReadFile(file.Name())
+		if err != nil {
+			t.Fatalf("Failed to read file: %v", err)
+		}
+		if string(content) != expected {
+			t.Errorf("Expected content %q, got %q", expected, string(content))
+		}
+	}
+
+	// Invalid keys abort before writing
+	_, err = myWriter.WriteLogfmt(map[string]string{"bad key": "value"}, 2)
+	if err == nil {
+		t.Error("Expected error for invalid logfmt key, got nil")
+	}
+
+	err = myWriter.CloseAllConns()
+	if err != nil {
+		t.Errorf("CloseAllConns returned error: %v", err)
+	}
+}
+
+// Test Dwriter used as a shared sink from many goroutines -> run with -race
+func TestDWriterConcurrentSink(t *testing.T) {
+	err := writer.Dwriter.FactoryReset()
+	if err != nil {
+		t.Fatalf("FactoryReset returned error: %v", err)
+	}
+
+	const goroutines = 8
+	const iterations = 5
+
+	var allFiles []*os.File
+	var filesMu sync.Mutex
+	wg := sync.WaitGroup{}
+
+	for g := 0; g < goroutines; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < iterations; i++ {
+				newFiles := makeFiles(1)
+				filesMu.Lock()
+				allFiles = append(allFiles, newFiles...)
+				filesMu.Unlock()
+
+				if err := writer.Dwriter.AddFiles(newFiles); err != nil {
+					t.Errorf("AddFiles returned error: %v", err)
+					continue
+				}
+				results, err := writer.Dwriter.Write(2)
+				if err != nil {
+					// A concurrent FactoryReset may have cleared the files
+					if !strings.Contains(err.Error(), "files is empty") {
+						t.Errorf("Write returned error: %v", err)
+					}
+					continue
+				}
+				if results.Failure != 0 || results.Success != results.Total {
+					t.Errorf("Inconsistent results: total %d, success %d, failure %d",
+						results.Total, results.Success, results.Failure)
+				}
+			}
+		}()
+	}
+
+	// FactoryReset must wait for in-flight writes instead of clobbering them
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 3; i++ {
+			if err := writer.Dwriter.FactoryReset(); err != nil {
+				t.Errorf("FactoryReset returned error: %v", err)
+			}
+			time.Sleep(time.Millisecond)
+		}
+	}()
+
+	wg.Wait()
+
+	err = writer.Dwriter.FactoryReset()
+	if err != nil {
+		t.Errorf("FactoryReset returned error: %v", err)
+	}
+	cleanupFiles(allFiles)
+}
+
+// Test mixing setters with writes on the shared Dwriter (run with -race)
+func TestDWriterConcurrentSettings(t *testing.T) {
+	err := writer.Dwriter.FactoryReset()
+	if err != nil {
+		t.Fatalf("FactoryReset returned error: %v", err)
+	}
+	oldMessage := writer.Dwriter.GetMessage()
+	defer writer.Dwriter.SetMessage(oldMessage)
+	oldRetries := writer.Dwriter.GetRetries()
+	defer writer.Dwriter.SetRetries(oldRetries)
+	defer writer.Dwriter.SetContext(context.Background())
+
+	myFiles := makeFiles(2)
+	defer cleanupFiles(myFiles)
+	if err := writer.Dwriter.AddFiles(myFiles); err != nil {
+		t.Fatalf("AddFiles returned error: %v", err)
+	}
+
+	const goroutines = 4
+	const iterations = 5
+	wg := sync.WaitGroup{}
+
+	// Goroutines writing their own message atomically
+	for g := 0; g < goroutines; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < iterations; i++ {
+				tag := fmt.Sprintf("<tag-%d>", g)
+				if _, err := writer.Dwriter.WriteMessage(tag, 2); err != nil {
+					t.Errorf("WriteMessage returned error: %v", err)
+				}
+			}
+		}(g)
+	}
+
+	// Goroutines changing the settings between shared writes
+	for g := 0; g < goroutines; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < iterations; i++ {
+				shared := fmt.Sprintf("[shared-%d]", g)
+				if err := writer.Dwriter.SetMessage(&shared); err != nil {
+					t.Errorf("SetMessage returned error: %v", err)
+				}
+				writer.Dwriter.SetContext(context.Background())
+				if err := writer.Dwriter.SetRetries(uint64(i)); err != nil {
+					t.Errorf("SetRetries returned error: %v", err)
+				}
+				if _, err := writer.Dwriter.Write(2); err != nil {
+					t.Errorf("Write returned error: %v", err)
+				}
+				_ = writer.Dwriter.GetMessage()
+				_ = writer.Dwriter.GetRetries()
+			}
+		}(g)
+	}
+
+	wg.Wait()
+
+	err = writer.Dwriter.FactoryReset()
+	if err != nil {
+		t.Errorf("FactoryReset returned error: %v", err)
+	}
+
+	// Every atomic write landed with its own message
+	for _, file := range myFiles {
+		content, err := os.ReadFile(file.Name())
+		if err != nil {
+			t.Fatalf("Failed to read file: %v", err)
+		}
+		for g := 0; g < goroutines; g++ {
+			tag := fmt.Sprintf("<tag-%d>", g)
+			if count := strings.Count(string(content), tag); count != iterations {
+				t.Errorf("Expected %d writes of %s, got %d", iterations, tag, count)
+			}
+		}
+	}
+}
+
+// Test progress-bar contract -> TotalTargets + progress channel
+func TestProgressBarContract(t *testing.T) {
+	myFiles := makeFiles(5)
+	defer cleanupFiles(myFiles)
+
+	myWriter := writer.NewWriter(&myFiles, modeA, &message, 10, 3, 100)
+
+	progressCh := make(chan writer.Progress)
+	myWriter.SetProgressChannel(progressCh)
+
+	// Simulated progress bar consumer
+	total := myWriter.TotalTargets()
+	if total != 5 {
+		t.Fatalf("Expected 5 total targets, got %d", total)
+	}
+	percentCh := make(chan float64, 1)
+	go func() {
+		var percent float64
+		seen := 0
+		for event := range progressCh {
+			seen++
+			if event.Total != total {
+				t.Errorf("Expected event total %d, got %d", total, event.Total)
+			}
+			if event.Err != nil {
+				t.Errorf("Unexpected error for %s: %v", event.FileName, event.Err)
+			}
+			percent = float64(event.Completed) / float64(total) * 100
+			if seen == total {
+				break
+			}
+		}
+		percentCh <- percent
+	}()
+
+	results, err := myWriter.Write(2)
+	if err != nil {
+		t.Fatalf("Write returned error: %v", err)
+	}
+	if results.Success != 5 {
+		t.Errorf("Expected 5 successful writes, got %d", results.Success)
+	}
+
+	select {
+	case percent := <-percentCh:
+		if percent != 100 {
+			t.Errorf("Expected progress to reach 100%%, got %.1f%%", percent)
+		}
+	case <-time.After(time.Second):
+		t.Error("Progress consumer did not finish")
+	}
+
+	myWriter.SetProgressChannel(nil)
+	err = myWriter.CloseAllConns()
+	if err != nil {
+		t.Errorf("CloseAllConns returned error: %v", err)
+	}
+}
+
+// Test lazy truncation keeps mtime for unchanged files
+func TestLazyTruncate(t *testing.T) {
+	myFiles := makeFiles(1)
+	defer cleanupFiles(myFiles)
+
+	modeW, _ := writer.NewMode(&appendModeW)
+	myWriter := writer.NewWriter(&myFiles, modeW, &message, 10, 3, 100)
+	myWriter.SetLazyTruncate(true)
+
+	if !myWriter.IsLazyTruncate() {
+		t.Error("Expected lazy truncate to be enabled")
+	}
+
+	_, err := myWriter.Write(1)
+	if err != nil {
+		t.Fatalf("Write returned error: %v", err)
+	}
+	firstInfo, err := os.Stat(myFiles[0].Name())
+	if err != nil {
+		t.Fatalf("Stat returned error: %v", err)
+	}
+
+	time.Sleep(20 * time.Millisecond)
+
+	results, err := myWriter.Write(1)
+	if err != nil {
+		t.Fatalf("Write returned error: %v", err)
+	}
+	if results.Success != 1 {
+		t.Errorf("Expected 1 successful write, got %d", results.Success)
+	}
+	secondInfo, err := os.Stat(myFiles[0].Name())
+	if err != nil {
+		t.Fatalf("Stat returned error: %v", err)
+	}
+
+	if !secondInfo.ModTime().Equal(firstInfo.ModTime()) {
+		t.Errorf("Expected mtime to be unchanged, got %v then %v", firstInfo.ModTime(), secondInfo.ModTime())
+	}
+
+	content, err := os.ReadFile(myFiles[0].Name())
+	if err != nil {
+		t.Fatalf("Failed to read file: %v", err)
+	}
+	if string(content) != message {
+		t.Errorf("Expected content '%s', got '%s'", message, string(content))
+	}
+
+	err = myWriter.CloseAllConns()
+	if err != nil {
+		t.Errorf("CloseAllConns returned error: %v", err)
+	}
+}
+
+// Test after write hook failures are recorded as failures
+func TestAfterWriteHook(t *testing.T) {
+	myFiles := makeFiles(3)
+	defer cleanupFiles(myFiles)
+
+	myWriter := writer.NewWriter(&myFiles, modeA, &message, 10, 3, 100)
+
+	failingFile := myFiles[1].Name()
+	var calls int
+	var callsMu sync.Mutex
+	myWriter.SetAfterWrite(func(fileName string, r *writer.Results) error {
+		callsMu.Lock()
+		calls++
+		callsMu.Unlock()
+		if fileName == failingFile {
+			return fmt.Errorf("notification failed")
+		}
+		return nil
+	})
+
+	results, err := myWriter.Write(2)
+	if err != nil {
+		t.Fatalf("Write returned error: %v", err)
+	}
+	if calls != 3 {
+		t.Errorf("Expected hook to be called 3 times, got %d", calls)
+	}
+	if results.Success != 2 {
+		t.Errorf("Expected 2 successful writes, got %d", results.Success)
+	}
+	if results.Failure != 1 {
+		t.Errorf("Expected 1 failure, got %d", results.Failure)
+	}
+	info, ok := results.Info[failingFile].(string)
+	if !ok || !strings.Contains(info, "notification failed") {
+		t.Errorf("Expected hook error in Info for %s, got %v", failingFile, results.Info[failingFile])
+	}
+
+	// Getters can be called from the hook while the write is running
+	done := make(chan error, 1)
+	myWriter.SetAfterWrite(func(fileName string, r *writer.Results) error {
+		if *myWriter.GetMessage() != message || myWriter.GetRetries() != 3 {
+			return fmt.Errorf("unexpected settings read from hook")
+		}
+		return nil
+	})
+	go func() {
+		results, err := myWriter.Write(2)
+		if err == nil && results.Failure != 0 {
+			err = *results.ErrSlice[0]
+		}
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("Write returned error: %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Expected getters called from the hook not to block the write")
+	}
+
+	err = myWriter.CloseAllConns()
+	if err != nil {
+		t.Errorf("CloseAllConns returned error: %v", err)
+	}
+}
+
+// Test truncation impact reports current file sizes
+func TestTruncationImpact(t *testing.T) {
+	myFiles := makeFiles(3)
+	defer cleanupFiles(myFiles)
+
+	sizes := []int{0, 10, 1000}
+	for i, size := range sizes {
+		err := os.WriteFile(myFiles[i].Name(), []byte(strings.Repeat("x", size)), 0666)
+		if err != nil {
+			t.Fatalf("Failed to write file: %v", err)
+		}
+	}
+
+	modeW, _ := writer.NewMode(&appendModeW)
+	myWriter := writer.NewWriter(&myFiles, modeW, &message, 10, 3, 100)
+
+	impact := myWriter.TruncationImpact()
+	if len(impact) != 3 {
+		t.Fatalf("Expected impact for 3 files, got %d", len(impact))
+	}
+	for i, size := range sizes {
+		if impact[myFiles[i].Name()] != int64(size) {
+			t.Errorf("Expected impact %d for %s, got %d", size, myFiles[i].Name(), impact[myFiles[i].Name()])
+		}
+	}
+}
+
+// Test invalid paths collected as pre-failures instead of failing construction
+func TestNewWriterFromJSONContinueOnOpenError(t *testing.T) {
+	config := []byte(`{
+		"files": ["valid-*.txt", "invalid/path-*.txt"],
+		"mode": "a",
+		"message": "test message",
+		"maxPool": 10,
+		"retries": 0,
+		"backoff": 100,
+		"continueOnOpenError": true
+	}`)
+
+	myWriter, err := writer.NewWriterFromJSON(config)
+	if err != nil {
+		t.Fatalf("NewWriterFromJSON returned error: %v", err)
+	}
+	defer cleanupFiles(*myWriter.GetFiles())
+
+	if len(*myWriter.GetFiles()) != 1 {
+		t.Errorf("Expected 1 valid file, got %d", len(*myWriter.GetFiles()))
+	}
+	if _, ok := myWriter.GetOpenErrors()["invalid/path-*.txt"]; !ok {
+		t.Error("Expected open error for invalid path")
+	}
+	if myWriter.TotalTargets() != 2 {
+		t.Errorf("Expected 2 targets including the invalid path, got %d", myWriter.TotalTargets())
+	}
+
+	// Progress covers the invalid path, and the getter can be read from a hook
+	progressCh := make(chan writer.Progress, 2)
+	myWriter.SetProgressChannel(progressCh)
+	var hookErrs int
+	myWriter.SetReportFunc(func(r *writer.Results) {
+		hookErrs = len(myWriter.GetOpenErrors())
+	})
+	results, err := myWriter.Write(2)
+	if err != nil {
+		t.Fatalf("Write returned error: %v", err)
+	}
+	close(progressCh)
+	var last writer.Progress
+	for event := range progressCh {
+		last = event
+	}
+	if last.Completed != 2 || last.Total != 2 {
+		t.Errorf("Expected progress to complete 2 of 2 targets, got %d of %d", last.Completed, last.Total)
+	}
+	if hookErrs != 1 {
+		t.Errorf("Expected 1 open error read from the report hook, got %d", hookErrs)
+	}
+	if results.Total != 2 {
+		t.Errorf("Expected total of 2, got %d", results.Total)
+	}
+	if results.Success != 1 {
+		t.Errorf("Expected 1 successful write, got %d", results.Success)
+	}
+	if results.Failure != 1 {
+		t.Errorf("Expected 1 failure, got %d", results.Failure)
+	}
+
+	// Without the option construction fails
+	config = []byte(`{"files": ["invalid/path-*.txt"], "mode": "a", "message": "test message"}`)
+	_, err = writer.NewWriterFromJSON(config)
+	if err == nil {
+		t.Error("Expected error for invalid path, got nil")
+	}
+
+	// Every path invalid -> the write still reports the open errors
+	config = []byte(`{"files": ["invalid/path-*.txt", "invalid/other-*.txt"], "mode": "a", "message": "test message", "continueOnOpenError": true}`)
+	invalidWriter, err := writer.NewWriterFromJSON(config)
+	if err != nil {
+		t.Fatalf("NewWriterFromJSON returned error: %v", err)
+	}
+	results, err = invalidWriter.Write(2)
+	if err != nil {
+		t.Fatalf("Write returned error: %v", err)
+	}
+	if results.Total != 2 || results.Failure != 2 || results.FailureRate != 1 {
+		t.Errorf("Expected 2 of 2 failed writes, got %d of %d", results.Failure, results.Total)
+	}
+	if results.ErrorFor("invalid/other-*.txt") == nil {
+		t.Error("Expected an error recorded for the invalid path")
+	}
+
+	err = myWriter.CloseAllConns()
+	if err != nil {
+		t.Errorf("CloseAllConns returned error: %v", err)
+	}
+}
+
+// Test adaptive workers change the active worker count during a write
+func TestAdaptiveWorkers(t *testing.T) {
+	myFiles := makeFiles(40)
+	defer cleanupFiles(myFiles)
+
+	myWriter := writer.NewWriter(&myFiles, modeA, &message, 40, 0, 100)
+	myWriter.SetAdaptiveWorkers(true)
+	err := myWriter.SetAdaptiveWorkerBounds(1, 8)
+	if err != nil {
+		t.Fatalf("SetAdaptiveWorkerBounds returned error: %v", err)
+	}
+	if err = myWriter.SetAdaptiveWorkerBounds(0, 8); err == nil {
+		t.Error("Expected error for min workers lower than 1, got nil")
+	}
+
+	// Simulate storage getting slower halfway through the run
+	var samples []int
+	var samplesMu sync.Mutex
+	myWriter.SetAfterWrite(func(fileName string, r *writer.Results) error {
+		samplesMu.Lock()
+		samples = append(samples, myWriter.ActiveWorkers())
+		written := len(samples)
+		samplesMu.Unlock()
+		if written > 20 {
+			time.Sleep(10 * time.Millisecond)
+		} else {
+			time.Sleep(time.Millisecond)
+		}
+		return nil
+	})
+
+	results, err := myWriter.Write(8)
+	if err != nil {
+		t.Fatalf("Write returned error: %v", err)
+	}
+	if results.Success != 40 {
+		t.Errorf("Expected 40 successful writes, got %d", results.Success)
+	}
+
+	minSeen, maxSeen := samples[0], samples[0]
+	for _, active := range samples {
+		if active < minSeen {
+			minSeen = active
+		}
+		if active > maxSeen {
+			maxSeen = active
+		}
+	}
+	if minSeen == maxSeen {
+		t.Errorf("Expected active worker count to change, stayed at %d", minSeen)
+	}
+	if minSeen < 1 || maxSeen > 8 {
+		t.Errorf("Expected active workers within [1, 8], got [%d, %d]", minSeen, maxSeen)
+	}
+	t.Logf("Active worker samples: %v", samples)
+
+	if myWriter.ActiveWorkers() != 0 {
+		t.Errorf("Expected 0 active workers after write, got %d", myWriter.ActiveWorkers())
+	}
+
+	err = myWriter.CloseAllConns()
+	if err != nil {
+		t.Errorf("CloseAllConns returned error: %v", err)
+	}
+}
+
+// Test looking up errors by file name
+func TestResultsErrorFor(t *testing.T) {
+	myFiles := makeFiles(4)
+	defer cleanupFiles(myFiles)
+
+	myWriter := writer.NewWriter(&myFiles, modeA, &message, 10, 0, 100)
+
+	failing := map[string]string{
+		myFiles[0].Name(): "first failure",
+		myFiles[2].Name(): "second failure",
+	}
+	myWriter.SetAfterWrite(func(fileName string, r *writer.Results) error {
+		if reason, ok := failing[fileName]; ok {
+			return fmt.Errorf("%s", reason)
+		}
+		return nil
+	})
+
+	results, err := myWriter.Write(2)
+	if err != nil {
+		t.Fatalf("Write returned error: %v", err)
+	}
+	if results.Failure != 2 {
+		t.Errorf("Expected 2 failures, got %d", results.Failure)
+	}
+
+	for _, file := range myFiles {
+		fileErr := results.ErrorFor(file.Name())
+		reason, shouldFail := failing[file.Name()]
+		if shouldFail {
+			if fileErr == nil || !strings.Contains(fileErr.Error(), reason) {
+				t.Errorf("Expected error containing '%s' for %s, got %v", reason, file.Name(), fileErr)
+			}
+		} else if fileErr != nil {
+			t.Errorf("Expected nil error for %s, got %v", file.Name(), fileErr)
+		}
+	}
+	if results.ErrorFor("unknown-file") != nil {
+		t.Error("Expected nil error for unknown file")
+	}
+
+	err = myWriter.CloseAllConns()
+	if err != nil {
+		t.Errorf("CloseAllConns returned error: %v", err)
+	}
+}
+
+// Test CRLF newline style for line based writes
+func TestNewlineStyleCRLF(t *testing.T) {
+	myFiles := makeFiles(2)
+	defer cleanupFiles(myFiles)
+
+	myWriter := writer.NewWriter(&myFiles, modeA, &message, 10, 3, 100)
+	if myWriter.GetNewlineStyle() != writer.NewlineLF {
+		t.Error("Expected default newline style to be LF")
+	}
+	err := myWriter.SetNewlineStyle(writer.NewlineCRLF)
+	if err != nil {
+		t.Fatalf("SetNewlineStyle returned error: %v", err)
+	}
+
+	lines := []map[string]string{
+		{"level": "info", "msg": "first line"},
+		{"level": "warn", "msg": "second line"},
+	}
+	for _, fields := range lines {
+		if _, err := myWriter.WriteLogfmt(fields, 2); err != nil {
+			t.Fatalf("WriteLogfmt returned error: %v", err)
+		}
+	}
+
+	for _, file := range myFiles {
+		content, err := os.ReadFile(file.Name())
+		if err != nil {
+			t.Fatalf("Failed to read file: %v", err)
+		}
+		written := strings.SplitAfter(string(content), "\n")
+		written = written[:len(written)-1] // Drop empty remainder after last line ending
+		if len(written) != len(lines) {
+			t.Fatalf("Expected %d lines, got %d: %q", len(lines), len(written), string(content))
+		}
+		for _, line := range written {
+			if !strings.HasSuffix(line, "\r\n") {
+				t.Errorf("Expected line to end with CRLF, got %q", line)
+			}
+		}
+	}
+
+	err = myWriter.CloseAllConns()
+	if err != nil {
+		t.Errorf("CloseAllConns returned error: %v", err)
+	}
+}
+
+// Test replacing files from paths
+func TestSetFilesFromPaths(t *testing.T) {
+	oldFiles := makeFiles(2)
+	defer cleanupFiles(oldFiles)
+
+	myWriter := writer.NewWriter(&oldFiles, modeA, &message, 10, 3, 100)
+	if _, err := myWriter.Write(2); err != nil {
+		t.Fatalf("Write returned error: %v", err)
+	}
+
+	dir := t.TempDir()
+	paths := []string{filepath.Join(dir, "first.txt"), filepath.Join(dir, "second.txt")}
+	err := myWriter.SetFilesFromPaths(paths)
+	if err != nil {
+		t.Fatalf("SetFilesFromPaths returned error: %v", err)
+	}
+	if len(*myWriter.GetFiles()) != 2 {
+		t.Fatalf("Expected 2 files, got %d", len(*myWriter.GetFiles()))
+	}
+
+	newMessage := "new paths message"
+	err = myWriter.SetMessage(&newMessage)
+	if err != nil {
+		t.Fatalf("SetMessage returned error: %v", err)
+	}
+	results, err := myWriter.Write(2)
+	if err != nil {
+		t.Fatalf("Write returned error: %v", err)
+	}
+	if results.Success != 2 {
+		t.Errorf("Expected 2 successful writes, got %d", results.Success)
+	}
+
+	for _, path := range paths {
+		content, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatalf("Failed to read file: %v", err)
+		}
+		if string(content) != newMessage {
+			t.Errorf("Expected content '%s', got '%s'", newMessage, string(content))
+		}
+	}
+	// Old files did not receive the new message
+	for _, file := range oldFiles {
+		content, _ := os.ReadFile(file.Name())
+		if strings.Contains(string(content), newMessage) {
+			t.Errorf("Expected old file %s to be untouched", file.Name())
+		}
+	}
+
+	// Invalid paths fail without the continue option
+	err = myWriter.SetFilesFromPaths([]string{filepath.Join(dir, "missing", "file.txt")})
+	if err == nil {
+		t.Error("Expected error for invalid path, got nil")
+	}
+	if len(*myWriter.GetFiles()) != 2 {
+		t.Errorf("Expected files to be unchanged, got %d files", len(*myWriter.GetFiles()))
+	}
+
+	// With the continue option they are collected as pre-failures
+	myWriter.SetContinueOnOpenError(true)
+	missing := filepath.Join(dir, "missing", "file.txt")
+	err = myWriter.SetFilesFromPaths([]string{paths[0], missing})
+	if err != nil {
+		t.Fatalf("SetFilesFromPaths returned error: %v", err)
+	}
+	if _, ok := myWriter.GetOpenErrors()[missing]; !ok {
+		t.Error("Expected open error for invalid path")
+	}
+	results, err = myWriter.Write(2)
+	if err != nil {
+		t.Fatalf("Write returned error: %v", err)
+	}
+	if results.Total != 2 || results.Success != 1 || results.Failure != 1 {
+		t.Errorf("Expected 1 success and 1 failure of 2, got %d and %d of %d", results.Success, results.Failure, results.Total)
+	}
+
+	err = myWriter.CloseAllConns()
+	if err != nil {
+		t.Errorf("CloseAllConns returned error: %v", err)
+	}
+}
+
+// Test canceling a running write from the Writer itself
+func TestWriterCancel(t *testing.T) {
+	myFiles := makeFiles(200)
+	defer cleanupFiles(myFiles)
+
+	myWriter := writer.NewWriter(&myFiles, modeA, &message, 200, 3, 100)
+
+	// Slow down each file so the write is still running when canceled
+	started := make(chan struct{})
+	var once sync.Once
+	myWriter.SetAfterWrite(func(fileName string, r *writer.Results) error {
+		once.Do(func() { close(started) })
+		time.Sleep(5 * time.Millisecond)
+		return nil
+	})
+
+	type outcome struct {
+		results *writer.Results
+		err     error
+	}
+	done := make(chan outcome, 1)
+	go func() {
+		results, err := myWriter.Write(2)
+		done <- outcome{results, err}
+	}()
+
+	<-started
+	cancelTime := time.Now()
+	myWriter.Cancel()
+
+	select {
+	case out := <-done:
+		elapsed := time.Since(cancelTime)
+		if elapsed > 200*time.Millisecond {
+			t.Errorf("Expected prompt termination after Cancel, took %v", elapsed)
+		}
+		if out.err == nil && out.results.Success == 200 {
+			t.Error("Expected write to be interrupted, all files were written")
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Write did not terminate after Cancel")
+	}
+
+	// Cancel without a running write is a no-op
+	myWriter.Cancel()
+
+	err := myWriter.CloseAllConns()
+	if err != nil {
+		t.Errorf("CloseAllConns returned error: %v", err)
+	}
+}
+
+// Test writing the same JSON document to each file
+func TestWriteJSON(t *testing.T) {
+	myFiles := makeFiles(3)
+	defer cleanupFiles(myFiles)
+
+	modeW, _ := writer.NewMode(&appendModeW)
+	myWriter := writer.NewWriter(&myFiles, modeW, &message, 10, 3, 100)
+	myWriter.SetJSONIndent("  ")
+
+	type config struct {
+		Name    string   `json:"name"`
+		Port    int      `json:"port"`
+		Enabled bool     `json:"enabled"`
+		Tags    []string `json:"tags"`
+	}
+	expected := config{Name: "service", Port: 8080, Enabled: true, Tags: []string{"a", "b"}}
+
+	results, err := myWriter.WriteJSON(expected, 2)
+	if err != nil {
+		t.Fatalf("WriteJSON returned error: %v", err)
+	}
+	if results.Success != 3 {
+		t.Errorf("Expected 3 successful writes, got %d", results.Success)
+	}
+
+	for _, file := range myFiles {
+		content, err := os.ReadFile(file.Name())
+		if err != nil {
+			t.Fatalf("Failed to read file: %v", err)
+		}
+		if !strings.Contains(string(content), "\n  \"name\"") {
+			t.Errorf("Expected indented JSON, got %q", string(content))
+		}
+		var got config
+		if err := json.Unmarshal(content, &got); err != nil {
+			t.Fatalf("Failed to unmarshal file content: %v", err)
+		}
+		if got.Name != expected.Name || got.Port != expected.Port || got.Enabled != expected.Enabled || len(got.Tags) != 2 {
+			t.Errorf("Expected %+v, got %+v", expected, got)
+		}
+	}
+
+	// Marshal errors abort before writing
+	_, err = myWriter.WriteJSON(map[string]interface{}{"bad": make(chan int)}, 2)
+	if err == nil {
+		t.Error("Expected marshal error, got nil")
+	}
+
+	err = myWriter.CloseAllConns()
+	if err != nil {
+		t.Errorf("CloseAllConns returned error: %v", err)
+	}
+}
+
+// Test dispatching files largest-first
+func TestSortBySize(t *testing.T) {
+	myFiles := makeFiles(5)
+	defer cleanupFiles(myFiles)
+
+	sizes := []int{100, 5000, 10, 2000, 500}
+	for i, size := range sizes {
+		err := os.WriteFile(myFiles[i].Name(), []byte(strings.Repeat("x", size)), 0666)
+		if err != nil {
+			t.Fatalf("Failed to write file: %v", err)
+		}
+	}
+
+	myWriter := writer.NewWriter(&myFiles, modeA, &message, 10, 3, 100)
+	myWriter.SetSortBySize(true)
+
+	// A single worker processes files in dispatch order
+	var order []string
+	myWriter.SetAfterWrite(func(fileName string, r *writer.Results) error {
+		order = append(order, fileName)
+		return nil
+	})
+	if _, err := myWriter.Write(1); err != nil {
+		t.Fatalf("Write returned error: %v", err)
+	}
+
+	expected := []string{myFiles[1].Name(), myFiles[3].Name(), myFiles[4].Name(), myFiles[0].Name(), myFiles[2].Name()}
+	if len(order) != len(expected) {
+		t.Fatalf("Expected %d dispatched files, got %d", len(expected), len(order))
+	}
+	for i := range expected {
+		if order[i] != expected[i] {
+			t.Errorf("Expected file %d to be %s, got %s", i, expected[i], order[i])
+		}
+	}
+
+	err := myWriter.CloseAllConns()
+	if err != nil {
+		t.Errorf("CloseAllConns returned error: %v", err)
+	}
+}
+
+// Test sorting by size does not increase total time with mixed file sizes. The time
+// is derived from the dispatch order rather than measured, so it does not depend on
+// the scheduler: each file takes time proportional to its size and is handed to the
+// first free worker.
+func TestSortBySizeTiming(t *testing.T) {
+	myFiles := makeFiles(5)
+	defer cleanupFiles(myFiles)
+
+	// Largest file last -> worst case for unsorted dispatch
+	sizes := make(map[string]int)
+	for i, file := range myFiles {
+		size := 10
+		if i == len(myFiles)-1 {
+			size = 1000
+		}
+		sizes[file.Name()] = size
+		err := os.WriteFile(file.Name(), []byte(strings.Repeat("x", size)), 0666)
+		if err != nil {
+			t.Fatalf("Failed to write file: %v", err)
+		}
+	}
+
+	// A single worker processes files in dispatch order
+	dispatchOrder := func(sorted bool) []string {
+		myWriter := writer.NewWriter(&myFiles, modeA, &message, 10, 3, 100)
+		myWriter.SetSortBySize(sorted)
+		var order []string
+		myWriter.SetAfterWrite(func(fileName string, r *writer.Results) error {
+			order = append(order, fileName)
+			return nil
+		})
+		if _, err := myWriter.Write(1); err != nil {
+			t.Fatalf("Write returned error: %v", err)
+		}
+		if err := myWriter.CloseAllConns(); err != nil {
+			t.Errorf("CloseAllConns returned error: %v", err)
+		}
+		if len(order) != len(myFiles) {
+			t.Fatalf("Expected %d dispatched files, got %d", len(myFiles), len(order))
+		}
+		return order
+	}
+
+	// Total time of the dispatch order with the given number of workers
+	totalTime := func(order []string, workers int) int {
+		busy := make([]int, workers)
+		for _, fileName := range order {
+			free := 0
+			for i := range busy {
+				if busy[i] < busy[free] {
+					free = i
+				}
+			}
+			busy[free] += sizes[fileName]
+		}
+		return slices.Max(busy)
+	}
+
+	unsorted := totalTime(dispatchOrder(false), 2)
+	sorted := totalTime(dispatchOrder(true), 2)
+	t.Logf("Unsorted: %d, sorted: %d", unsorted, sorted)
+	if sorted > unsorted {
+		t.Errorf("Expected sorted dispatch to be no slower than unsorted, got %d vs %d", sorted, unsorted)
+	}
+	if sorted != 1000 {
+		t.Errorf("Expected the small files to be written alongside the largest, got total %d", sorted)
+	}
+}
+
+// Test appending only new lines
+func TestWriteLineDelta(t *testing.T) {
+	myFiles := makeFiles(2)
+	defer cleanupFiles(myFiles)
+
+	myWriter := writer.NewWriter(&myFiles, modeA, &message, 10, 3, 100)
+
+	_, err := myWriter.WriteLineDelta([]string{"alpha", "beta", "gamma"}, 2)
+	if err != nil {
+		t.Fatalf("WriteLineDelta returned error: %v", err)
+	}
+	results, err := myWriter.WriteLineDelta([]string{"beta", "gamma", "delta", "epsilon", "delta"}, 2)
+	if err != nil {
+		t.Fatalf("WriteLineDelta returned error: %v", err)
+	}
+	if results.Success != 2 {
+		t.Errorf("Expected 2 successful writes, got %d", results.Success)
+	}
+
+	expected := "alpha\nbeta\ngamma\ndelta\nepsilon\n"
+	for _, file := range myFiles {
+		content, err := os.ReadFile(file.Name())
+		if err != nil {
+			t.Fatalf("Failed to read file: %v", err)
+		}
+		if string(content) != expected {
+			t.Errorf("Expected content %q, got %q", expected, string(content))
+		}
+	}
+
+	// Re-running the same lines appends nothing
+	_, err = myWriter.WriteLineDelta([]string{"alpha", "epsilon"}, 2)
+	if err != nil {
+		t.Fatalf("WriteLineDelta returned error: %v", err)
+	}
+	content, _ := os.ReadFile(myFiles[0].Name())
+	if string(content) != expected {
+		t.Errorf("Expected content to be unchanged, got %q", string(content))
+	}
+
+	// 'w' mode is rejected -> the delta would replace the content
+	modeW, _ := writer.NewMode(&appendModeW)
+	wWriter := writer.NewWriter(&myFiles, modeW, &message, 10, 3, 100)
+	_, err = wWriter.WriteLineDelta([]string{"zeta"}, 2)
+	if err == nil {
+		t.Error("Expected error for 'w' mode, got nil")
+	}
+	content, _ = os.ReadFile(myFiles[0].Name())
+	if string(content) != expected {
+		t.Errorf("Expected content to be unchanged, got %q", string(content))
+	}
+
+	err = myWriter.CloseAllConns()
+	if err != nil {
+		t.Errorf("CloseAllConns returned error: %v", err)
+	}
+}
+
+// Test recovering un-applied writes from the WAL
+func TestWALRecovery(t *testing.T) {
+	walDir := filepath.Join(t.TempDir(), "wal")
+	targetDir := filepath.Join(t.TempDir(), "targets")
+	if err := os.MkdirAll(targetDir, 0755); err != nil {
+		t.Fatalf("Failed to create target dir: %v", err)
+	}
+
+	// Targets whose directory disappears -> writes cannot be applied (simulated crash)
+	myFiles := make([]*os.File, 2)
+	for i := range myFiles {
+		file, err := os.Create(filepath.Join(targetDir, fmt.Sprintf("target%d.txt", i)))
+		if err != nil {
+			t.Fatalf("Failed to create target: %v", err)
+		}
+		file.Close()
+		myFiles[i] = file
+	}
+	if err := os.RemoveAll(targetDir); err != nil {
+		t.Fatalf("Failed to remove target dir: %v", err)
+	}
+
+	myWriter := writer.NewWriter(&myFiles, modeA, &message, 10, 0, 100)
+	err := myWriter.SetWAL(walDir)
+	if err != nil {
+		t.Fatalf("SetWAL returned error: %v", err)
+	}
+
+	results, err := myWriter.Write(2)
+	if err != nil {
+		t.Fatalf("Write returned error: %v", err)
+	}
+	if results.Failure != 2 {
+		t.Fatalf("Expected 2 failed writes, got %d", results.Failure)
+	}
+	pending, _ := filepath.Glob(filepath.Join(walDir, "*.wal"))
+	if len(pending) != 2 {
+		t.Fatalf("Expected 2 pending WAL entries, got %d", len(pending))
+	}
+
+	// Restart -> targets are reachable again and the WAL is replayed
+	if err := os.MkdirAll(targetDir, 0755); err != nil {
+		t.Fatalf("Failed to recreate target dir: %v", err)
+	}
+	recovered := writer.NewWriter(nil, modeA, &message, 10, 0, 100)
+	if err := recovered.SetWAL(walDir); err != nil {
+		t.Fatalf("SetWAL returned error: %v", err)
+	}
+	results, err = recovered.RecoverWAL()
+	if err != nil {
+		t.Fatalf("RecoverWAL returned error: %v", err)
+	}
+	if results.Success != 2 {
+		t.Errorf("Expected 2 replayed writes, got %d", results.Success)
+	}
+
+	for _, file := range myFiles {
+		content, err := os.ReadFile(file.Name())
+		if err != nil {
+			t.Fatalf("Failed to read target: %v", err)
+		}
+		if string(content) != message {
+			t.Errorf("Expected content '%s', got '%s'", message, string(content))
+		}
+	}
+	pending, _ = filepath.Glob(filepath.Join(walDir, "*.wal"))
+	if len(pending) != 0 {
+		t.Errorf("Expected no pending WAL entries after recovery, got %d", len(pending))
+	}
+
+	// Successful writes leave no WAL entries behind
+	results, err = myWriter.Write(2)
+	if err != nil {
+		t.Fatalf("Write returned error: %v", err)
+	}
+	if results.Success != 2 {
+		t.Errorf("Expected 2 successful writes, got %d", results.Success)
+	}
+	pending, _ = filepath.Glob(filepath.Join(walDir, "*.wal"))
+	if len(pending) != 0 {
+		t.Errorf("Expected no WAL entries after successful write, got %d", len(pending))
+	}
+
+	err = myWriter.CloseAllConns()
+	if err != nil {
+		t.Errorf("CloseAllConns returned error: %v", err)
+	}
+}
+
+// Test checksum footer verification and corruption detection
+func TestChecksumFooter(t *testing.T) {
+	myFiles := makeFiles(2)
+	defer cleanupFiles(myFiles)
+
+	myWriter := writer.NewWriter(&myFiles, modeA, &message, 10, 3, 100)
+	myWriter.SetChecksumFooter(true)
+
+	// Two appended records, each with its own footer
+	var results *writer.Results
+	for i := 0; i < 2; i++ {
+		var err error
+		results, err = myWriter.Write(2)
+		if err != nil {
+			t.Fatalf("Write returned error: %v", err)
+		}
+		if results.Success != 2 {
+			t.Errorf("Expected 2 successful writes, got %d", results.Success)
+		}
+	}
+
+	// Each file holds two records, as many bytes as one write to both files
+	written, err := os.ReadFile(myFiles[0].Name())
+	if err != nil {
+		t.Fatalf("Failed to read file: %v", err)
+	}
+	if results.BytesWritten != uint64(len(written)) {
+		t.Errorf("Expected %d bytes written including footers, got %d", len(written), results.BytesWritten)
+	}
+
+	for _, file := range myFiles {
+		ok, err := writer.VerifyChecksumFooter(file.Name())
+		if err != nil {
+			t.Fatalf("VerifyChecksumFooter returned error: %v", err)
+		}
+		if !ok {
+			t.Errorf("Expected checksum of %s to verify", file.Name())
+		}
+	}
+
+	// Corrupt the content of the first file
+	content, err := os.ReadFile(myFiles[0].Name())
+	if err != nil {
+		t.Fatalf("Failed to read file: %v", err)
+	}
+	if !strings.Contains(string(content), "# sha256:") {
+		t.Fatalf("Expected checksum footer in content, got %q", string(content))
+	}
+	corrupted := strings.Replace(string(content), "test", "TEST", 1)
+	if err := os.WriteFile(myFiles[0].Name(), []byte(corrupted), 0666); err != nil {
+		t.Fatalf("Failed to corrupt file: %v", err)
+	}
+	ok, err := writer.VerifyChecksumFooter(myFiles[0].Name())
+	if err != nil {
+		t.Fatalf("VerifyChecksumFooter returned error: %v", err)
+	}
+	if ok {
+		t.Error("Expected corrupted file to fail verification")
+	}
+
+	// Files without a footer return an error
+	plain := makeFiles(1)
+	defer cleanupFiles(plain)
+	if _, err := writer.VerifyChecksumFooter(plain[0].Name()); err == nil {
+		t.Error("Expected error for file without checksum footer, got nil")
+	}
+
+	err = myWriter.CloseAllConns()
+	if err != nil {
+		t.Errorf("CloseAllConns returned error: %v", err)
+	}
+}
+
+// Test colorized Results.Print output
+func TestResultPrintColor(t *testing.T) {
+	previous := writer.IsColorOutput()
+	defer writer.SetColorOutput(previous)
+
+	results := writer.NewResults()
+	results.Total = 2
+	results.Success = 1
+	results.Failure = 1
+
+	capture := func() string {
+		reader, pipeWriter, err := os.Pipe()
+		if err != nil {
+			t.Fatalf("Failed to create pipe: %v", err)
+		}
+		stdout := os.Stdout
+		os.Stdout = pipeWriter
+		results.Print()
+		os.Stdout = stdout
+		pipeWriter.Close()
+
+		output, err := io.ReadAll(reader)
+		if err != nil {
+			t.Fatalf("Failed to read output: %v", err)
+		}
+		return string(output)
+	}
+
+	writer.SetColorOutput(true)
+	output := capture()
+	if !strings.Contains(output, "\033[32mSuccess: 1") {
+		t.Errorf("Expected green success line, got %q", output)
+	}
+	if !strings.Contains(output, "\033[31mFailure: 1") {
+		t.Errorf("Expected red failure line, got %q", output)
+	}
+	if !strings.Contains(output, "\033[31mFailure Rate:") {
+		t.Errorf("Expected red failure rate line, got %q", output)
+	}
+
+	// A clean run does not flag failures
+	results.Success = 2
+	results.Failure = 0
+	output = capture()
+	if strings.Contains(output, "\033[31m") {
+		t.Errorf("Expected no red lines without failures, got %q", output)
+	}
+	if !strings.Contains(output, "Failure: 0") || !strings.Contains(output, "Failure Rate:") {
+		t.Errorf("Expected failure lines without color, got %q", output)
+	}
+
+	writer.SetColorOutput(false)
+	output = capture()
+	if strings.Contains(output, "\033[") {
+		t.Errorf("Expected no ANSI codes with color disabled, got %q", output)
+	}
+}
+
+// Test all-or-nothing transactional writes
+func TestWriteTransactional(t *testing.T) {
+	goodDir := t.TempDir()
+	badDir := filepath.Join(t.TempDir(), "bad")
+	if err := os.MkdirAll(badDir, 0755); err != nil {
+		t.Fatalf("Failed to create dir: %v", err)
+	}
+
+	paths := []string{
+		filepath.Join(goodDir, "first.txt"),
+		filepath.Join(goodDir, "second.txt"),
+		filepath.Join(badDir, "third.txt"),
+	}
+	original := "original content"
+	myFiles := make([]*os.File, len(paths))
+	for i, path := range paths {
+		if err := os.WriteFile(path, []byte(original), 0644); err != nil {
+			t.Fatalf("Failed to write file: %v", err)
+		}
+		file, err := os.Open(path)
+		if err != nil {
+			t.Fatalf("Failed to open file: %v", err)
+		}
+		file.Close()
+		myFiles[i] = file
+	}
+
+	// The third target cannot be staged
+	if err := os.RemoveAll(badDir); err != nil {
+		t.Fatalf("Failed to remove dir: %v", err)
+	}
+
+	modeW, _ := writer.NewMode(&appendModeW)
+	myWriter := writer.NewWriter(&myFiles, modeW, &message, 10, 0, 100)
+
+	results, err := myWriter.WriteTransactional(2)
+	if err == nil {
+		t.Fatal("Expected rollback error, got nil")
+	}
+	if results.Success != 0 || results.Failure != 3 {
+		t.Errorf("Expected 0 successes and 3 failures, got %d and %d", results.Success, results.Failure)
+	}
+	for _, path := range paths[:2] {
+		content, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatalf("Failed to read file: %v", err)
+		}
+		if string(content) != original {
+			t.Errorf("Expected %s to be unchanged, got '%s'", path, string(content))
+		}
+	}
+	if _, err := os.Stat(paths[2]); !os.IsNotExist(err) {
+		t.Errorf("Expected %s to not exist, got %v", paths[2], err)
+	}
+	entries, _ := os.ReadDir(goodDir)
+	if len(entries) != 2 {
+		t.Errorf("Expected temp files to be removed, found %d entries", len(entries))
+	}
+
+	// Every target available -> all files are committed
+	if err := os.MkdirAll(badDir, 0755); err != nil {
+		t.Fatalf("Failed to recreate dir: %v", err)
+	}
+	results, err = myWriter.WriteTransactional(2)
+	if err != nil {
+		t.Fatalf("WriteTransactional returned error: %v", err)
+	}
+	if results.Success != 3 {
+		t.Errorf("Expected 3 successes, got %d", results.Success)
+	}
+	for _, path := range paths {
+		content, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatalf("Failed to read file: %v", err)
+		}
+		if string(content) != message {
+			t.Errorf("Expected '%s' in %s, got '%s'", message, path, string(content))
+		}
+	}
+
+	// Append mode is rejected
+	myWriter.SetMode(modeA)
+	if _, err := myWriter.WriteTransactional(2); err == nil {
+		t.Error("Expected error in 'a' mode, got nil")
+	}
+}
+
+// Test gzip compression reports uncompressed and compressed byte counts
+func TestCompressionBytes(t *testing.T) {
+	myFiles := makeFiles(2)
+	defer cleanupFiles(myFiles)
+
+	compressible := strings.Repeat("compressible log line\n", 200)
+	myWriter := writer.NewWriter(&myFiles, modeA, &compressible, 10, 3, 100)
+	myWriter.SetCompression(true)
+
+	results, err := myWriter.Write(2)
+	if err != nil {
+		t.Fatalf("Write returned error: %v", err)
+	}
+	if results.Success != 2 {
+		t.Errorf("Expected 2 successful writes, got %d", results.Success)
+	}
+	if results.BytesWritten != uint64(2*len(compressible)) {
+		t.Errorf("Expected %d bytes written, got %d", 2*len(compressible), results.BytesWritten)
+	}
+	if results.CompressedBytes == 0 || results.CompressedBytes >= results.BytesWritten {
+		t.Errorf("Expected compressed bytes below %d, got %d", results.BytesWritten, results.CompressedBytes)
+	}
+	if ratio := results.CompressionRatio(); ratio <= 0 || ratio >= 1 {
+		t.Errorf("Expected compression ratio between 0 and 1, got %f", ratio)
+	}
+
+	for _, file := range myFiles {
+		compressed, err := os.ReadFile(file.Name())
+		if err != nil {
+			t.Fatalf("Failed to read file: %v", err)
+		}
+		if uint64(len(compressed))*2 != results.CompressedBytes {
+			t.Errorf("Expected %d compressed bytes on disk, got %d", results.CompressedBytes/2, len(compressed))
+		}
+		reader, err := gzip.NewReader(bytes.NewReader(compressed))
+		if err != nil {
+			t.Fatalf("Failed to open gzip reader: %v", err)
+		}
+		content, err := io.ReadAll(reader)
+		if err != nil {
+			t.Fatalf("Failed to decompress file: %v", err)
+		}
+		if string(content) != compressible {
+			t.Errorf("Expected decompressed content to match message")
+		}
+	}
+
+	err = myWriter.CloseAllConns()
+	if err != nil {
+		t.Errorf("CloseAllConns returned error: %v", err)
+	}
+}
+
+// Test detecting distinct handles sharing the same file name
+func TestDuplicateFileNames(t *testing.T) {
+	myFiles := makeFiles(2)
+	defer cleanupFiles(myFiles)
+
+	duplicate, err := os.OpenFile(myFiles[0].Name(), os.O_RDWR, 0666)
+	if err != nil {
+		t.Fatalf("Failed to open duplicate handle: %v", err)
+	}
+	defer duplicate.Close()
+
+	collision := []*os.File{myFiles[0], myFiles[1], duplicate}
+	myWriter := writer.NewWriter(&collision, modeA, &message, 10, 3, 100)
+
+	_, err = myWriter.Write(2)
+	if err == nil {
+		t.Fatal("Expected duplicate name error, got nil")
+	}
+	if !strings.Contains(err.Error(), myFiles[0].Name()) {
+		t.Errorf("Expected error to name %s, got: %v", myFiles[0].Name(), err)
+	}
+
+	// The same handle listed twice is not a conflict
+	repeated := []*os.File{myFiles[0], myFiles[0]}
+	err = myWriter.SetFiles(&repeated)
+	if err != nil {
+		t.Fatalf("SetFiles returned error: %v", err)
+	}
+	if _, err = myWriter.Write(2); err != nil {
+		t.Errorf("Write returned error for repeated handle: %v", err)
+	}
+
+	err = myWriter.CloseAllConns()
+	if err != nil {
+		t.Errorf("CloseAllConns returned error: %v", err)
+	}
+}
+
+// Test skipping identical writes within the dedup window
+func TestWriteDedupTTL(t *testing.T) {
+	myFiles := makeFiles(3)
+	defer cleanupFiles(myFiles)
+
+	myWriter := writer.NewWriter(&myFiles, modeA, &message, 10, 3, 100)
+	myWriter.SetWriteDedupTTL(200 * time.Millisecond)
+
+	if myWriter.GetWriteDedupTTL() != 200*time.Millisecond {
+		t.Errorf("Expected dedup TTL 200ms, got %v", myWriter.GetWriteDedupTTL())
+	}
+
+	// First write goes through
+	results, err := myWriter.Write(2)
+	if err != nil {
+		t.Fatalf("Write returned error: %v", err)
+	}
+	if results.Deduped != 0 {
+		t.Errorf("Expected 0 deduped writes, got %d", results.Deduped)
+	}
+
+	// Same content within the TTL is deduped
+	results, err = myWriter.Write(2)
+	if err != nil {
+		t.Fatalf("Write returned error: %v", err)
+	}
+	if results.Deduped != 3 || results.Success != 3 {
+		t.Errorf("Expected 3 deduped successful writes, got deduped %d, success %d", results.Deduped, results.Success)
+	}
+	for _, file := range myFiles {
+		if results.Info[file.Name()] != "deduped" {
+			t.Errorf("Expected info 'deduped' for %s, got %v", file.Name(), results.Info[file.Name()])
+		}
+	}
+
+	// Same content outside the TTL is written again
+	time.Sleep(250 * time.Millisecond)
+	results, err = myWriter.Write(2)
+	if err != nil {
+		t.Fatalf("Write returned error: %v", err)
+	}
+	if results.Deduped != 0 {
+		t.Errorf("Expected 0 deduped writes after TTL, got %d", results.Deduped)
+	}
+
+	err = myWriter.CloseAllConns()
+	if err != nil {
+		t.Errorf("CloseAllConns returned error: %v", err)
+	}
+
+	// Only the first and third writes reached the files
+	for _, file := range myFiles {
+		content, err := os.ReadFile(file.Name())
+		if err != nil {
+			t.Fatalf("Failed to read file: %v", err)
+		}
+		if string(content) != message+message {
+			t.Errorf("Expected content written twice, got %q", string(content))
+		}
+	}
+}
+
+// Test compressing small records with a preset dictionary
+func TestCompressionDict(t *testing.T) {
+	record := `{"level":"info","service":"checkout","msg":"order placed","order_id":73519,"status":"ok"}` + "\n"
+
+	// Dictionary built from sample records
+	var samples strings.Builder
+	for i := 0; i < 20; i++ {
+		fmt.Fprintf(&samples, `{"level":"info","service":"checkout","msg":"order placed","order_id":%d,"status":"ok"}`+"\n", 10000+i*37)
+	}
+	dict := []byte(samples.String())
+
+	compressedSize := func(dict []byte) (uint64, []*os.File) {
+		myFiles := makeFiles(1)
+		myWriter := writer.NewWriter(&myFiles, modeA, &record, 10, 3, 100)
+		myWriter.SetCompression(true)
+		myWriter.SetCompressionDict(dict)
+		results, err := myWriter.Write(1)
+		if err != nil {
+			t.Fatalf("Write returned error: %v", err)
+		}
+		if results.Success != 1 {
+			t.Fatalf("Expected 1 successful write, got %d", results.Success)
+		}
+		err = myWriter.CloseAllConns()
+		if err != nil {
+			t.Errorf("CloseAllConns returned error: %v", err)
+		}
+		return results.CompressedBytes, myFiles
+	}
+
+	dictSize, dictFiles := compressedSize(dict)
+	defer cleanupFiles(dictFiles)
+
+	// Baseline -> the same zlib stream without a dictionary
+	var plain bytes.Buffer
+	zlibWriter := zlib.NewWriter(&plain)
+	zlibWriter.Write([]byte(record))
+	zlibWriter.Close()
+	if dictSize >= uint64(plain.Len()) {
+		t.Errorf("Expected dictionary output below %d bytes, got %d", plain.Len(), dictSize)
+	}
+
+	// Stream header carries the preset dictionary flag (FDICT)
+	compressed, err := os.ReadFile(dictFiles[0].Name())
+	if err != nil {
+		t.Fatalf("Failed to read file: %v", err)
+	}
+	if len(compressed) < 2 || compressed[1]&0x20 == 0 {
+		t.Errorf("Expected a zlib header with the FDICT bit set, got % x", compressed[:min(len(compressed), 2)])
+	}
+
+	// Getter returns a copy
+	myFiles := makeFiles(1)
+	defer cleanupFiles(myFiles)
+	myWriter := writer.NewWriter(&myFiles, modeA, &record, 10, 3, 100)
+	myWriter.SetCompressionDict(dict)
+	got := myWriter.GetCompressionDict()
+	if !bytes.Equal(got, dict) {
+		t.Errorf("Expected GetCompressionDict to return the dictionary")
+	}
+	got[0] ^= 0xff
+	if !bytes.Equal(myWriter.GetCompressionDict(), dict) {
+		t.Errorf("Expected GetCompressionDict to return a copy")
+	}
+
+	// The getter can be read from a hook while the write is running
+	var hookDict []byte
+	myWriter.SetAfterWrite(func(fileName string, r *writer.Results) error {
+		hookDict = myWriter.GetCompressionDict()
+		return nil
+	})
+	if _, err := myWriter.Write(1); err != nil {
+		t.Fatalf("Write returned error: %v", err)
+	}
+	if !bytes.Equal(hookDict, dict) {
+		t.Errorf("Expected the dictionary read from the hook")
+	}
+
+	// Output decompresses with the same dictionary
+	reader, err := zlib.NewReaderDict(bytes.NewReader(compressed), dict)
+	if err != nil {
+		t.Fatalf("Failed to open zlib reader: %v", err)
+	}
+	content, err := io.ReadAll(reader)
+	if err != nil {
+		t.Fatalf("Failed to decompress file: %v", err)
+	}
+	if string(content) != record {
+		t.Errorf("Expected decompressed content %q, got %q", record, string(content))
+	}
+}
+
+// Test checkpointing between writes without closing connections
+func TestCheckpoint(t *testing.T) {
+	myFiles := makeFiles(2)
+	defer cleanupFiles(myFiles)
+
+	first := "before checkpoint\n"
+	second := "after checkpoint\n"
+	myWriter := writer.NewWriter(&myFiles, modeA, &first, 10, 3, 100)
+
+	_, err := myWriter.Write(2)
+	if err != nil {
+		t.Fatalf("Write returned error: %v", err)
+	}
+	err = myWriter.Checkpoint()
+	if err != nil {
+		t.Fatalf("Checkpoint returned error: %v", err)
+	}
+
+	// Connections stay pooled after the checkpoint
+	for _, file := range myFiles {
+		if _, ok := myWriter.GetOpenFilesPool().Load(file.Name()); !ok {
+			t.Errorf("Expected %s to stay pooled after checkpoint", file.Name())
+		}
+		content, err := os.ReadFile(file.Name())
+		if err != nil {
+			t.Fatalf("Failed to read file: %v", err)
+		}
+		if string(content) != first {
+			t.Errorf("Expected checkpointed content %q, got %q", first, string(content))
+		}
+	}
+
+	err = myWriter.SetMessage(&second)
+	if err != nil {
+		t.Fatalf("SetMessage returned error: %v", err)
+	}
+	_, err = myWriter.Write(2)
+	if err != nil {
+		t.Fatalf("Write returned error: %v", err)
+	}
+	err = myWriter.Checkpoint()
+	if err != nil {
+		t.Fatalf("Checkpoint returned error: %v", err)
+	}
+
+	for _, file := range myFiles {
+		content, err := os.ReadFile(file.Name())
+		if err != nil {
+			t.Fatalf("Failed to read file: %v", err)
+		}
+		if string(content) != first+second {
+			t.Errorf("Expected content %q, got %q", first+second, string(content))
+		}
+	}
+
+	err = myWriter.CloseAllConns()
+	if err != nil {
+		t.Errorf("CloseAllConns returned error: %v", err)
+	}
+}
+
+// Test accumulating several writes into the same Results
+func TestWriteInto(t *testing.T) {
+	myFiles := makeFiles(3)
+	defer cleanupFiles(myFiles)
+
+	myWriter := writer.NewWriter(&myFiles, modeA, &message, 10, 3, 100)
+	results := writer.NewResults()
+
+	err := myWriter.WriteInto(results, 2)
+	if err != nil {
+		t.Fatalf("WriteInto returned error: %v", err)
+	}
+	if results.Total != 3 || results.Success != 3 {
+		t.Errorf("Expected 3 total and 3 successes, got %d and %d", results.Total, results.Success)
+	}
+
+	// Second write to a smaller file set adds to the totals
+	err = myWriter.SetFilesFromPaths([]string{myFiles[0].Name(), myFiles[1].Name()})
+	if err != nil {
+		t.Fatalf("SetFilesFromPaths returned error: %v", err)
+	}
+
+	err = myWriter.WriteInto(results, 2)
+	if err != nil {
+		t.Fatalf("WriteInto returned error: %v", err)
+	}
+	if results.Total != 5 || results.Success != 5 {
+		t.Errorf("Expected cumulative 5 total and 5 successes, got %d and %d", results.Total, results.Success)
+	}
+	if results.BytesWritten != uint64(5*len(message)) {
+		t.Errorf("Expected %d cumulative bytes, got %d", 5*len(message), results.BytesWritten)
+	}
+	if results.SuccessRate != 1 {
+		t.Errorf("Expected success rate 1, got %f", results.SuccessRate)
+	}
+
+	if err := myWriter.WriteInto(nil, 2); err == nil {
+		t.Error("Expected error for nil results")
+	}
+
+	err = myWriter.CloseAllConns()
+	if err != nil {
+		t.Errorf("CloseAllConns returned error: %v", err)
+	}
+}
+
+// Test bounding retries by elapsed time
+func TestMaxRetryDuration(t *testing.T) {
+	targetDir := filepath.Join(t.TempDir(), "targets")
+	if err := os.MkdirAll(targetDir, 0755); err != nil {
+		t.Fatalf("Failed to create target dir: %v", err)
+	}
+
+	// Target whose directory disappears -> every attempt fails
+	file, err := os.Create(filepath.Join(targetDir, "target.txt"))
+	if err != nil {
+		t.Fatalf("Failed to create target: %v", err)
+	}
+	file.Close()
+	if err := os.RemoveAll(targetDir); err != nil {
+		t.Fatalf("Failed to remove target dir: %v", err)
+	}
+	myFiles := []*os.File{file}
+
+	// 50 retries with doubling backoff would take far longer than the budget
+	budget := 300 * time.Millisecond
+	myWriter := writer.NewWriter(&myFiles, modeA, &message, 10, 50, 100)
+	myWriter.SetMaxRetryDuration(budget)
+	if myWriter.GetMaxRetryDuration() != budget {
+		t.Errorf("Expected max retry duration %v, got %v", budget, myWriter.GetMaxRetryDuration())
+	}
+
+	start := time.Now()
+	results, err := myWriter.Write(1)
+	elapsed := time.Since(start)
+	if err != nil {
+		t.Fatalf("Write returned error: %v", err)
+	}
+	if results.Failure != 1 {
+		t.Fatalf("Expected 1 failed write, got %d", results.Failure)
+	}
+	if elapsed < budget || elapsed > budget+500*time.Millisecond {
+		t.Errorf("Expected retries to stop at about %v, took %v", budget, elapsed)
+	}
+	if fileErr := results.ErrorFor(file.Name()); fileErr == nil || !strings.Contains(fileErr.Error(), "exceeded retry duration") {
+		t.Errorf("Expected retry duration error, got: %v", fileErr)
+	}
+}
+
+// Test reporting partial progress on cancellation
+func TestCancelErrorProgress(t *testing.T) {
+	myFiles := makeFiles(100)
+	defer cleanupFiles(myFiles)
+
+	myWriter := writer.NewWriter(&myFiles, modeA, &message, 100, 3, 100)
+
+	// Cancel once some files are done, while the rest are still pending
+	var written sync.WaitGroup
+	written.Add(10)
+	var count int64
+	var countLock sync.Mutex
+	myWriter.SetAfterWrite(func(fileName string, r *writer.Results) error {
+		countLock.Lock()
+		count++
+		if count <= 10 {
+			written.Done()
+		}
+		countLock.Unlock()
+		time.Sleep(5 * time.Millisecond)
+		return nil
+	})
+	go func() {
+		written.Wait()
+		myWriter.Cancel()
+	}()
+
+	results, err := myWriter.Write(2)
+	var cancelErr *writer.CancelError
+	if !errors.As(err, &cancelErr) {
+		t.Fatalf("Expected CancelError, got: %v", err)
+	}
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected error to wrap context.Canceled, got: %v", err)
+	}
+	if !cancelErr.Started {
+		t.Error("Expected cancellation mid-batch")
+	}
+	if cancelErr.Completed < 10 || cancelErr.Completed >= 100 || cancelErr.Total != 100 {
+		t.Errorf("Expected partial progress of 100 files, got %d of %d", cancelErr.Completed, cancelErr.Total)
+	}
+	if results == nil {
+		t.Fatal("Expected partial results with the cancellation")
+	}
+	if results.Success != uint64(cancelErr.Completed) {
+		t.Errorf("Expected %d successes in results, got %d", cancelErr.Completed, results.Success)
+	}
+	if _, ok := results.Info["canceled"]; !ok {
+		t.Error("Expected cancellation reason in results info")
+	}
+
+	// A canceled context before the write is reported as never started
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	myWriter.SetContext(ctx)
+	results, err = myWriter.Write(2)
+	if !errors.As(err, &cancelErr) || cancelErr.Started || cancelErr.Completed != 0 {
+		t.Errorf("Expected never started CancelError, got: %v", err)
+	}
+	if results != nil {
+		t.Error("Expected nil results for a write that never started")
+	}
+
+	err = myWriter.CloseAllConns()
+	if err != nil {
+		t.Errorf("CloseAllConns returned error: %v", err)
+	}
+}
+
+// Test prewarming pooled connections before a write
+func TestPrewarm(t *testing.T) {
+	myFiles := makeFiles(2)
+	defer cleanupFiles(myFiles)
+
+	myWriter := writer.NewWriter(&myFiles, modeA, &message, 10, 3, 100)
+
+	err := myWriter.Prewarm(myFiles[0].Name(), myFiles[1].Name())
+	if err != nil {
+		t.Fatalf("Prewarm returned error: %v", err)
+	}
+
+	// Prewarmed connections are pooled
+	prewarmed := make(map[string]*os.File)
+	for _, file := range myFiles {
+		pooled, ok := myWriter.GetOpenFilesPool().Load(file.Name())
+		if !ok {
+			t.Fatalf("Expected %s to be pooled after Prewarm", file.Name())
+		}
+		prewarmed[file.Name()] = pooled.(*os.File)
+	}
+
+	results, err := myWriter.Write(2)
+	if err != nil {
+		t.Fatalf("Write returned error: %v", err)
+	}
+	if results.Success != 2 {
+		t.Errorf("Expected 2 successful writes, got %d", results.Success)
+	}
+
+	// The write used the prewarmed connections without reopening
+	for _, file := range myFiles {
+		pooled, ok := myWriter.GetOpenFilesPool().Load(file.Name())
+		if !ok || pooled.(*os.File) != prewarmed[file.Name()] {
+			t.Errorf("Expected prewarmed connection of %s to be reused", file.Name())
+		}
+		content, err := os.ReadFile(file.Name())
+		if err != nil {
+			t.Fatalf("Failed to read file: %v", err)
+		}
+		if string(content) != message {
+			t.Errorf("Expected content %q, got %q", message, string(content))
+		}
+	}
+
+	// Unopenable files are reported
+	missing := filepath.Join(t.TempDir(), "missing", "file.txt")
+	if err := myWriter.Prewarm(missing); err == nil {
+		t.Error("Expected error prewarming a file in a missing directory")
+	}
+
+	err = myWriter.CloseAllConns()
+	if err != nil {
+		t.Errorf("CloseAllConns returned error: %v", err)
+	}
+}
+
+// Test pool hit and miss counters with adequate and undersized pools
+func TestPoolStats(t *testing.T) {
+	writeRepeatedly := func(maxPool uint64) writer.PoolStats {
+		myFiles := makeFiles(4)
+		defer cleanupFiles(myFiles)
+
+		myWriter := writer.NewWriter(&myFiles, modeA, &message, maxPool, 3, 100)
+		for i := 0; i < 5; i++ {
+			results, err := myWriter.Write(1)
+			if err != nil {
+				t.Fatalf("Write returned error: %v", err)
+			}
+			if results.Success != 4 {
+				t.Fatalf("Expected 4 successful writes, got %d", results.Success)
+			}
+		}
+		err := myWriter.CloseAllConns()
+		if err != nil {
+			t.Errorf("CloseAllConns returned error: %v", err)
+		}
+		return myWriter.PoolStats()
+	}
+
+	// Adequate pool -> only the first write misses
+	stats := writeRepeatedly(10)
+	if stats.Hits != 16 || stats.Misses != 4 || stats.Evictions != 0 {
+		t.Errorf("Expected 16 hits, 4 misses and no evictions, got %+v", stats)
+	}
+	if ratio := stats.HitRatio(); ratio < 0.8 {
+		t.Errorf("Expected a high hit ratio, got %f", ratio)
+	}
+
+	// Tiny pool -> connections are evicted before they are reused
+	stats = writeRepeatedly(1)
+	if ratio := stats.HitRatio(); ratio > 0.2 {
+		t.Errorf("Expected a low hit ratio, got %f (%+v)", ratio, stats)
+	}
+	if stats.Evictions == 0 {
+		t.Errorf("Expected evictions with a tiny pool, got %+v", stats)
+	}
+
+	if ratio := (writer.PoolStats{}).HitRatio(); ratio != 0 {
+		t.Errorf("Expected 0 hit ratio without lookups, got %f", ratio)
+	}
+}
+
+// Test deferring truncation in 'w' mode until the message is ready
+func TestDeferTruncate(t *testing.T) {
+	original := "original content\n"
+	path := filepath.Join(t.TempDir(), "target.txt")
+	if err := os.WriteFile(path, []byte(original), 0666); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+
+	modeW, _ := writer.NewMode(&appendModeW)
+	myWriter := writer.NewWriter(&[]*os.File{}, modeW, &message, 10, 3, 100)
+	myWriter.SetDeferTruncate(true)
+	if !myWriter.IsDeferTruncate() {
+		t.Error("Expected deferred truncation to be enabled")
+	}
+
+	// Opening the file does not truncate it
+	err := myWriter.SetFilesFromPaths([]string{path})
+	if err != nil {
+		t.Fatalf("SetFilesFromPaths returned error: %v", err)
+	}
+
+	// A message that cannot be generated leaves the content in place
+	_, err = myWriter.WriteJSON(make(chan int), 1)
+	if err == nil {
+		t.Fatal("Expected marshal error, got nil")
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read file: %v", err)
+	}
+	if string(content) != original {
+		t.Errorf("Expected content %q to be preserved, got %q", original, string(content))
+	}
+
+	// A ready message replaces the content
+	results, err := myWriter.WriteJSON(map[string]int{"version": 2}, 1)
+	if err != nil {
+		t.Fatalf("WriteJSON returned error: %v", err)
+	}
+	if results.Success != 1 {
+		t.Errorf("Expected 1 successful write, got %d", results.Success)
+	}
+	content, err = os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read file: %v", err)
+	}
+	if string(content) != `{"version":2}` {
+		t.Errorf("Expected truncated and rewritten content, got %q", string(content))
+	}
+
+	err = myWriter.CloseAllConns()
+	if err != nil {
+		t.Errorf("CloseAllConns returned error: %v", err)
+	}
+
+	// A write failing per file inside the write path, on a file opened by the Writer
+	failedWrite := func(deferTrunc bool) string {
+		dir := t.TempDir()
+		path := filepath.Join(dir, "target.txt")
+		walDir := filepath.Join(dir, "wal")
+
+		if err := os.WriteFile(path, []byte(original), 0666); err != nil {
+			t.Fatalf("Failed to write file: %v", err)
+		}
+		myWriter := writer.NewWriter(&[]*os.File{}, modeW, &message, 10, 0, 100)
+		myWriter.SetDeferTruncate(deferTrunc)
+		if err := myWriter.SetFilesFromPaths([]string{path}); err != nil {
+			t.Fatalf("SetFilesFromPaths returned error: %v", err)
+		}
+		defer myWriter.CloseAllConns()
+
+		// Unreachable WAL -> the write fails once its connection is open
+		if err := myWriter.SetWAL(walDir); err != nil {
+			t.Fatalf("SetWAL returned error: %v", err)
+		}
+		if err := os.RemoveAll(walDir); err != nil {
+			t.Fatalf("Failed to remove WAL dir: %v", err)
+		}
+		results, err := myWriter.Write(1)
+		if err != nil {
+			t.Fatalf("Write returned error: %v", err)
+		}
+		if results.Failure != 1 {
+			t.Fatalf("Expected 1 failed write, got %d", results.Failure)
+		}
+
+		content, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatalf("Failed to read file: %v", err)
+		}
+		return string(content)
+	}
+
+	if content := failedWrite(true); content != original {
+		t.Errorf("Expected content %q to be preserved, got %q", original, content)
+	}
+	if content := failedWrite(false); content != "" {
+		t.Errorf("Expected content to be truncated without deferred truncation, got %q", content)
+	}
+}
+
+// Test the report func receiving the outcome of every write
+func TestReportFunc(t *testing.T) {
+	myFiles := makeFiles(3)
+	defer cleanupFiles(myFiles)
+
+	myWriter := writer.NewWriter(&myFiles, modeA, &message, 10, 3, 100)
+	reports := make(chan *writer.Results, 2)
+	myWriter.SetReportFunc(func(r *writer.Results) {
+		reports <- r
+	})
+
+	// Synchronous write
+	results, err := myWriter.Write(2)
+	if err != nil {
+		t.Fatalf("Write returned error: %v", err)
+	}
+	select {
+	case report := <-reports:
+		if report != results {
+			t.Error("Expected the report to receive the returned results")
+		}
+		if report.Total != 3 || report.Success != 3 || report.SuccessRate != 1 {
+			t.Errorf("Expected 3 of 3 successful writes reported, got %d of %d", report.Success, report.Total)
+		}
+	default:
+		t.Fatal("Expected a report after Write")
+	}
+
+	// Fire-and-forget write
+	cancel, resultCh, errCh := writer.StartWriteWithCancel(myWriter, 2)
+	defer cancel()
+	select {
+	case report := <-reports:
+		if report.Total != 3 || report.Success != 3 {
+			t.Errorf("Expected 3 of 3 successful writes reported, got %d of %d", report.Success, report.Total)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected a report after the asynchronous write")
+	}
+	select {
+	case <-resultCh:
+	case err := <-errCh:
+		t.Errorf("Asynchronous write returned error: %v", err)
+	}
+
+	// WriteInto -> the report holds that write only
+	total := writer.NewResults()
+	for i := 0; i < 2; i++ {
+		if err := myWriter.WriteInto(total, 2); err != nil {
+			t.Fatalf("WriteInto returned error: %v", err)
+		}
+		report := <-reports
+		if report == total || report.Total != 3 || report.Success != 3 {
+			t.Errorf("Expected a report of 3 of 3 successful writes, got %d of %d", report.Success, report.Total)
+		}
+	}
+	if total.Total != 6 || total.Success != 6 || total.SuccessRate != 1 {
+		t.Errorf("Expected 6 of 6 successful writes accumulated, got %d of %d", total.Success, total.Total)
+	}
+
+	// Writes rejected before starting are reported as failed
+	duplicate, err := os.OpenFile(myFiles[0].Name(), os.O_WRONLY, 0666)
+	if err != nil {
+		t.Fatalf("Failed to open file: %v", err)
+	}
+	defer duplicate.Close()
+	duplicates := []*os.File{myFiles[0], duplicate}
+	dupWriter := writer.NewWriter(&duplicates, modeA, &message, 10, 3, 100)
+	dupWriter.SetReportFunc(func(r *writer.Results) {
+		reports <- r
+	})
+	if _, err := dupWriter.Write(2); err == nil {
+		t.Error("Expected error for duplicate file names, got nil")
+	}
+	select {
+	case report := <-reports:
+		if report.Total != 2 || report.Failure != 2 || report.FailureRate != 1 {
+			t.Errorf("Expected 2 of 2 failed writes reported, got %d of %d", report.Failure, report.Total)
+		}
+	default:
+		t.Fatal("Expected a report after the rejected write")
+	}
+
+	ctx, cancelCtx := context.WithCancel(context.Background())
+	cancelCtx()
+	myWriter.SetContext(ctx)
+	if _, err := myWriter.Write(2); err == nil {
+		t.Error("Expected error for canceled context, got nil")
+	}
+	select {
+	case report := <-reports:
+		if report.Total != 3 || report.Failure != 3 || report.ErrorFor(myFiles[0].Name()) == nil {
+			t.Errorf("Expected 3 of 3 failed writes reported, got %d of %d", report.Failure, report.Total)
+		}
+	default:
+		t.Fatal("Expected a report after the canceled write")
+	}
+
+	err = myWriter.CloseAllConns()
+	if err != nil {
+		t.Errorf("CloseAllConns returned error: %v", err)
+	}
+}
+
+// Test canceling an asynchronous write queued behind an in-flight write (run with -race)
+func TestStartWriteWithCancelOverlap(t *testing.T) {
+	myFiles := makeFiles(50)
+	defer cleanupFiles(myFiles)
+
+	myWriter := writer.NewWriter(&myFiles, modeA, &message, 100, 3, 100)
+
+	// Slow down each file and signal once the second write is underway
+	var count int64
+	var countLock sync.Mutex
+	firstStarted := make(chan struct{})
+	secondStarted := make(chan struct{})
+	myWriter.SetAfterWrite(func(fileName string, r *writer.Results) error {
+		countLock.Lock()
+		count++
+		switch count {
+		case 1:
+			close(firstStarted)
+		case 55:
+			close(secondStarted)
+		}
+		countLock.Unlock()
+		time.Sleep(2 * time.Millisecond)
+		return nil
+	})
+
+	firstDone := make(chan *writer.Results, 1)
+	go func() {
+		results, err := myWriter.Write(1)
+		if err != nil {
+			t.Errorf("Write returned error: %v", err)
+		}
+		firstDone <- results
+	}()
+	<-firstStarted
+
+	cancel, resultCh, errCh := writer.StartWriteWithCancel(myWriter, 1)
+	defer cancel()
+
+	// The in-flight write is not affected by the second write
+	first := <-firstDone
+	if first == nil || first.Success != 50 {
+		t.Fatalf("Expected the first write to complete, got %+v", first)
+	}
+
+	<-secondStarted
+	cancel()
+
+	select {
+	case err := <-errCh:
+		var cancelErr *writer.CancelError
+		if !errors.As(err, &cancelErr) || !cancelErr.Started {
+			t.Fatalf("Expected mid-batch CancelError, got: %v", err)
+		}
+		if cancelErr.Completed >= 50 {
+			t.Errorf("Expected cancel to stop the write, %d of 50 files completed", cancelErr.Completed)
+		}
+	case results := <-resultCh:
+		t.Fatalf("Expected cancel to take effect, all %d files were written", results.Success)
+	case <-time.After(2 * time.Second):
+		t.Fatal("Asynchronous write did not terminate after cancel")
+	}
+
+	// The Writer's own context is untouched -> later writes still run
+	myWriter.SetAfterWrite(nil)
+	results, err := myWriter.Write(2)
+	if err != nil || results.Success != 50 {
+		t.Errorf("Expected a later write to succeed, got %v", err)
+	}
+
+	err = myWriter.CloseAllConns()
+	if err != nil {
+		t.Errorf("CloseAllConns returned error: %v", err)
+	}
+}
+
+// Test lazy truncate replacing the content when the message changes
+func TestLazyTruncateChangedMessage(t *testing.T) {
+	myFiles := makeFiles(1)
+	defer cleanupFiles(myFiles)
+
+	first := "first content"
+	second := "second"
+	modeW, _ := writer.NewMode(&appendModeW)
+	myWriter := writer.NewWriter(&myFiles, modeW, &first, 10, 3, 100)
+	myWriter.SetLazyTruncate(true)
+
+	if _, err := myWriter.Write(1); err != nil {
+		t.Fatalf("Write returned error: %v", err)
+	}
+	if _, err := myWriter.WriteMessage(second, 1); err != nil {
+		t.Fatalf("WriteMessage returned error: %v", err)
+	}
+
+	content, err := os.ReadFile(myFiles[0].Name())
+	if err != nil {
+		t.Fatalf("Failed to read file: %v", err)
+	}
+	if string(content) != second {
+		t.Errorf("Expected content %q, got %q", second, string(content))
+	}
+
+	err = myWriter.CloseAllConns()
+	if err != nil {
+		t.Errorf("CloseAllConns returned error: %v", err)
+	}
+}
+
+// Test lazy truncate on files opened from paths and on compressed content
+func TestLazyTruncatePathsAndCompression(t *testing.T) {
+	modeW, _ := writer.NewMode(&appendModeW)
+	past := time.Now().Add(-time.Hour).Truncate(time.Second)
+
+	// Unchanged file -> skipped with its mtime kept
+	assertSkipped := func(myWriter *writer.Writer, path string) {
+		if err := os.Chtimes(path, past, past); err != nil {
+			t.Fatalf("Chtimes returned error: %v", err)
+		}
+		results, err := myWriter.Write(1)
+		if err != nil {
+			t.Fatalf("Write returned error: %v", err)
+		}
+		if results.Success != 1 || results.Info[path] != "unchanged" {
+			t.Errorf("Expected %s to be skipped as unchanged, got %v", path, results.Info[path])
+		}
+		info, err := os.Stat(path)
+		if err != nil {
+			t.Fatalf("Stat returned error: %v", err)
+		}
+		if !info.ModTime().Equal(past) {
+			t.Errorf("Expected mtime %v to be kept, got %v", past, info.ModTime())
+		}
+	}
+
+	// Opening from paths does not empty the file
+	path := filepath.Join(t.TempDir(), "target.txt")
+	if err := os.WriteFile(path, []byte(message), 0666); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	pathWriter := writer.NewWriter(&[]*os.File{}, modeW, &message, 10, 3, 100)
+	pathWriter.SetLazyTruncate(true)
+	if err := pathWriter.SetFilesFromPaths([]string{path}); err != nil {
+		t.Fatalf("SetFilesFromPaths returned error: %v", err)
+	}
+	assertSkipped(pathWriter, path)
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read file: %v", err)
+	}
+	if string(content) != message {
+		t.Errorf("Expected content %q, got %q", message, string(content))
+	}
+	if err := pathWriter.CloseAllConns(); err != nil {
+		t.Errorf("CloseAllConns returned error: %v", err)
+	}
+
+	// Compressed content is compared to the compressed message
+	myFiles := makeFiles(1)
+	defer cleanupFiles(myFiles)
+	compressWriter := writer.NewWriter(&myFiles, modeW, &message, 10, 3, 100)
+	compressWriter.SetLazyTruncate(true)
+	compressWriter.SetCompression(true)
+	if _, err := compressWriter.Write(1); err != nil {
+		t.Fatalf("Write returned error: %v", err)
+	}
+	assertSkipped(compressWriter, myFiles[0].Name())
+	compressed, err := os.ReadFile(myFiles[0].Name())
+	if err != nil {
+		t.Fatalf("Failed to read file: %v", err)
+	}
+	reader, err := gzip.NewReader(bytes.NewReader(compressed))
+	if err != nil {
+		t.Fatalf("Failed to open gzip reader: %v", err)
+	}
+	content, err = io.ReadAll(reader)
+	if err != nil {
+		t.Fatalf("Failed to decompress file: %v", err)
+	}
+	if string(content) != message {
+		t.Errorf("Expected decompressed content %q, got %q", message, string(content))
+	}
+	if err := compressWriter.CloseAllConns(); err != nil {
+		t.Errorf("CloseAllConns returned error: %v", err)
+	}
+}
+
+// Test transactional writes with compression and cancellation
+func TestWriteTransactionalCompressionAndCancel(t *testing.T) {
+	dir := t.TempDir()
+	original := "original content"
+	makeTargets := func(count int) []*os.File {
+		targets := make([]*os.File, count)
+		for i := range targets {
+			path := filepath.Join(dir, fmt.Sprintf("target%d.txt", i))
+			if err := os.WriteFile(path, []byte(original), 0644); err != nil {
+				t.Fatalf("Failed to write file: %v", err)
+			}
+			file, err := os.Open(path)
+			if err != nil {
+				t.Fatalf("Failed to open file: %v", err)
+			}
+			file.Close()
+			targets[i] = file
+		}
+		return targets
+	}
+
+	// Compressed staging
+	compressible := strings.Repeat("compressible log line\n", 200)
+	myFiles := makeTargets(2)
+	modeW, _ := writer.NewMode(&appendModeW)
+	myWriter := writer.NewWriter(&myFiles, modeW, &compressible, 10, 0, 100)
+	myWriter.SetCompression(true)
+
+	results, err := myWriter.WriteTransactional(2)
+	if err != nil {
+		t.Fatalf("WriteTransactional returned error: %v", err)
+	}
+	if results.CompressedBytes == 0 || results.CompressedBytes >= results.BytesWritten {
+		t.Errorf("Expected compressed bytes below %d, got %d", results.BytesWritten, results.CompressedBytes)
+	}
+	for _, file := range myFiles {
+		compressed, err := os.ReadFile(file.Name())
+		if err != nil {
+			t.Fatalf("Failed to read file: %v", err)
+		}
+		reader, err := gzip.NewReader(bytes.NewReader(compressed))
+		if err != nil {
+			t.Fatalf("Failed to open gzip reader: %v", err)
+		}
+		content, err := io.ReadAll(reader)
+		if err != nil || string(content) != compressible {
+			t.Errorf("Expected decompressed content to match message, got error: %v", err)
+		}
+	}
+
+	// A canceled context is reported as a CancelError
+	myWriter.SetCompression(false)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	myWriter.SetContext(ctx)
+	_, err = myWriter.WriteTransactional(2)
+	var cancelErr *writer.CancelError
+	if !errors.As(err, &cancelErr) || cancelErr.Started {
+		t.Errorf("Expected never started CancelError, got: %v", err)
+	}
+	myWriter.SetContext(context.Background())
+
+	// Cancel aborts the staging and rolls back
+	large := strings.Repeat("x", 1<<20)
+	manyFiles := makeTargets(200)
+	err = myWriter.SetFiles(&manyFiles)
+	if err != nil {
+		t.Fatalf("SetFiles returned error: %v", err)
+	}
+	err = myWriter.SetMessage(&large)
+	if err != nil {
+		t.Fatalf("SetMessage returned error: %v", err)
+	}
+	go func() {
+		for {
+			if staged, _ := filepath.Glob(filepath.Join(dir, ".*.tmp-*")); len(staged) > 0 {
+				myWriter.Cancel()
+				return
+			}
+			time.Sleep(time.Millisecond)
+		}
+	}()
+	results, err = myWriter.WriteTransactional(1)
+	if !errors.As(err, &cancelErr) || !cancelErr.Started {
+		t.Fatalf("Expected mid-batch CancelError, got: %v", err)
+	}
+	if results.Success != 0 || results.Failure != 200 {
+		t.Errorf("Expected all files rolled back, got %d successes", results.Success)
+	}
+	for _, file := range manyFiles {
+		content, err := os.ReadFile(file.Name())
+		if err != nil || string(content) != original {
+			t.Fatalf("Expected %s untouched after cancel", file.Name())
+		}
+	}
+	if staged, _ := filepath.Glob(filepath.Join(dir, ".*.tmp-*")); len(staged) != 0 {
+		t.Errorf("Expected temp files removed, found %d", len(staged))
+	}
+}
+
+// Test replaying WAL entries recorded with compression
+func TestWALRecoveryCompressed(t *testing.T) {
+	walDir := filepath.Join(t.TempDir(), "wal")
+	targetDir := filepath.Join(t.TempDir(), "targets")
+	if err := os.MkdirAll(targetDir, 0755); err != nil {
+		t.Fatalf("Failed to create target dir: %v", err)
+	}
+
+	// Target whose directory disappears -> the write cannot be applied
+	file, err := os.Create(filepath.Join(targetDir, "target.gz"))
+	if err != nil {
+		t.Fatalf("Failed to create target: %v", err)
+	}
+	file.Close()
+	if err := os.RemoveAll(targetDir); err != nil {
+		t.Fatalf("Failed to remove target dir: %v", err)
+	}
+	myFiles := []*os.File{file}
+
+	myWriter := writer.NewWriter(&myFiles, modeA, &message, 10, 0, 100)
+	myWriter.SetCompression(true)
+	if err := myWriter.SetWAL(walDir); err != nil {
+		t.Fatalf("SetWAL returned error: %v", err)
+	}
+	results, err := myWriter.Write(1)
+	if err != nil {
+		t.Fatalf("Write returned error: %v", err)
+	}
+	if results.Failure != 1 {
+		t.Fatalf("Expected 1 failed write, got %d", results.Failure)
+	}
+
+	// Replay -> the file holds a valid gzip stream of the message
+	if err := os.MkdirAll(targetDir, 0755); err != nil {
+		t.Fatalf("Failed to recreate target dir: %v", err)
+	}
+	results, err = myWriter.RecoverWAL()
+	if err != nil {
+		t.Fatalf("RecoverWAL returned error: %v", err)
+	}
+	if results.Success != 1 {
+		t.Fatalf("Expected 1 replayed write, got %d", results.Success)
+	}
+
+	compressed, err := os.ReadFile(file.Name())
+	if err != nil {
+		t.Fatalf("Failed to read file: %v", err)
+	}
+	reader, err := gzip.NewReader(bytes.NewReader(compressed))
+	if err != nil {
+		t.Fatalf("Failed to open gzip reader: %v", err)
+	}
+	content, err := io.ReadAll(reader)
+	if err != nil {
+		t.Fatalf("Failed to decompress file: %v", err)
+	}
+	if string(content) != message {
+		t.Errorf("Expected decompressed content %q, got %q", message, string(content))
+	}
+}
@@ -0,0 +1,121 @@
+//go:build unix
+
+package tests
+
+import (
+	writer "github.com/JuniorVieira99/jr_writer"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+)
+
+// Test forcing the permissions of created files under a restrictive umask
+func TestForcePerm(t *testing.T) {
+	oldMask := syscall.Umask(0077)
+	defer syscall.Umask(oldMask)
+
+	dir := t.TempDir()
+	masked := filepath.Join(dir, "masked.txt")
+	forced := filepath.Join(dir, "forced.txt")
+
+	myWriter := writer.NewWriter(&[]*os.File{}, modeA, &message, 10, 3, 100)
+
+	// Without ForcePerm the umask applies
+	err := myWriter.SetFilesFromPaths([]string{masked})
+	if err != nil {
+		t.Fatalf("SetFilesFromPaths returned error: %v", err)
+	}
+	info, err := os.Stat(masked)
+	if err != nil {
+		t.Fatalf("Failed to stat file: %v", err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("Expected umask permissions 0600, got %o", info.Mode().Perm())
+	}
+
+	// With ForcePerm the requested mode is kept
+	myWriter.SetForcePerm(true)
+	if !myWriter.IsForcePerm() {
+		t.Error("Expected ForcePerm to be enabled")
+	}
+	err = myWriter.SetFilesFromPaths([]string{forced})
+	if err != nil {
+		t.Fatalf("SetFilesFromPaths returned error: %v", err)
+	}
+	info, err = os.Stat(forced)
+	if err != nil {
+		t.Fatalf("Failed to stat file: %v", err)
+	}
+	if info.Mode().Perm() != 0666 {
+		t.Errorf("Expected forced permissions 0666, got %o", info.Mode().Perm())
+	}
+
+	// Existing files keep their permissions
+	err = myWriter.SetFilesFromPaths([]string{masked})
+	if err != nil {
+		t.Fatalf("SetFilesFromPaths returned error: %v", err)
+	}
+	info, err = os.Stat(masked)
+	if err != nil {
+		t.Fatalf("Failed to stat file: %v", err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("Expected existing permissions 0600, got %o", info.Mode().Perm())
+	}
+
+	err = myWriter.CloseAllConns()
+	if err != nil {
+		t.Errorf("CloseAllConns returned error: %v", err)
+	}
+}
+
+// Test the permissions of new targets of transactional writes
+func TestWriteTransactionalPerm(t *testing.T) {
+	oldMask := syscall.Umask(0022)
+	defer syscall.Umask(oldMask)
+
+	dir := t.TempDir()
+	newTarget := func(name string) *os.File {
+		file, err := os.Create(filepath.Join(dir, name))
+		if err != nil {
+			t.Fatalf("Failed to create file: %v", err)
+		}
+		file.Close()
+		os.Remove(file.Name())
+		return file
+	}
+
+	myFiles := []*os.File{newTarget("plain.txt")}
+	modeW, _ := writer.NewMode(&appendModeW)
+	myWriter := writer.NewWriter(&myFiles, modeW, &message, 10, 0, 100)
+
+	// New targets get the umask applied, like files created by Write
+	if _, err := myWriter.WriteTransactional(1); err != nil {
+		t.Fatalf("WriteTransactional returned error: %v", err)
+	}
+	info, err := os.Stat(myFiles[0].Name())
+	if err != nil {
+		t.Fatalf("Failed to stat file: %v", err)
+	}
+	if info.Mode().Perm() != 0644 {
+		t.Errorf("Expected permissions 0644, got %o", info.Mode().Perm())
+	}
+
+	// ForcePerm applies to new targets too
+	forced := []*os.File{newTarget("forced.txt")}
+	if err := myWriter.SetFiles(&forced); err != nil {
+		t.Fatalf("SetFiles returned error: %v", err)
+	}
+	myWriter.SetForcePerm(true)
+	if _, err := myWriter.WriteTransactional(1); err != nil {
+		t.Fatalf("WriteTransactional returned error: %v", err)
+	}
+	info, err = os.Stat(forced[0].Name())
+	if err != nil {
+		t.Fatalf("Failed to stat file: %v", err)
+	}
+	if info.Mode().Perm() != 0666 {
+		t.Errorf("Expected forced permissions 0666, got %o", info.Mode().Perm())
+	}
+}
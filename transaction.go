@@ -0,0 +1,232 @@
+package writer
+
+// Transactional Writes for the Writer Component
+// ----------------------------------------------------
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+)
+
+// ----------------------------------------------------
+// Structs
+// ----------------------------------------------------
+
+// stagedFile struct -> a target and the temp file holding its new content
+type stagedFile struct {
+	target string // Target file name
+	temp   string // Temp file name, empty if staging failed
+	size   int    // Bytes written to the temp file
+	err    error  // Staging error
+}
+
+// ----------------------------------------------------
+// Transaction Methods
+// ----------------------------------------------------
+
+// WriteTransactional writes the message to every file in the files slice with
+// all-or-nothing semantics. It requires 'w' mode.
+//
+// The message is first written to a temp file next to each target, using up to
+// maxWorkers workers (capped like Write). Only if every temp file was written and
+// synced successfully are they renamed over their targets. If any of them fails,
+// or the write is canceled while staging, all temp files are removed (rollback), no
+// target is modified, every file is recorded as failed and an error is returned
+// along with the Results. A cancellation returns a *CancelError.
+//
+// Temp files get the permissions of their existing target, or those of a file
+// created by the Writer for new targets (see SetForcePerm), and hold compressed
+// content when compression is enabled.
+//
+// Each rename is atomic, but the commit of several files is not: a rename failing
+// midway through the commit is reported as a failure for that file. Pooled
+// connections are closed after the commit, since they point to the replaced files.
+func (w *Writer) WriteTransactional(maxWorkers int) (*Results, error) {
+	w.opLock.Lock()
+	defer w.opLock.Unlock()
+
+	if err := w.fullWriteCheck(); err != nil {
+		return nil, err
+	}
+	if *w.mode.mode != "w" {
+		return nil, fmt.Errorf("transactional writes require 'w' mode, got: %s", *w.mode.mode)
+	}
+	if len(*w.files) == 0 {
+		err := fmt.Errorf("files is empty")
+		w.reportError(err)
+		return nil, err
+	}
+	if err := checkDuplicateNames(*w.files); err != nil {
+		w.reportError(err)
+		return nil, err
+	}
+	if ctxErr := w.ctx.Err(); ctxErr != nil {
+		err := &CancelError{Total: len(*w.files), Err: ctxErr}
+		w.reportError(err)
+		return nil, err
+	}
+
+	// Derive a cancelable context for this write -> used by Cancel
+	ctx, done := w.beginCancelable(w.ctx)
+	defer done()
+
+	message := *w.message
+	if w.checksum {
+		message = w.withChecksumFooter(message)
+	}
+
+	// Initialize Worker Count
+	maxWorkers = w.workerCount(maxWorkers, len(*w.files))
+
+	// Stage temp files
+	staged := make([]stagedFile, len(*w.files))
+	jobs := make(chan int, len(*w.files))
+	wg := sync.WaitGroup{}
+	for i := 0; i < maxWorkers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for index := range jobs {
+				file := (*w.files)[index]
+				if err := ctx.Err(); err != nil && file != nil {
+					staged[index] = stagedFile{target: file.Name(), err: err}
+					continue
+				}
+				staged[index] = w.stageFile(file, message)
+			}
+		}()
+	}
+	for index := range *w.files {
+		jobs <- index
+	}
+	close(jobs)
+	wg.Wait()
+
+	results := NewResults()
+	results.Total = uint64(len(staged))
+
+	// Rollback if any file failed or the write was canceled
+	var failed int
+	for _, file := range staged {
+		if file.err != nil {
+			failed++
+		}
+	}
+	ctxErr := ctx.Err()
+	if failed > 0 || ctxErr != nil {
+		for _, file := range staged {
+			if file.temp != "" {
+				os.Remove(file.temp)
+			}
+			err := file.err
+			if err == nil {
+				err = fmt.Errorf("file %s rolled back", file.target)
+			}
+			results.addFailure(file.target, err)
+			results.Info[file.target] = err.Error()
+		}
+		results.FailureRate = 1.0
+		Debug("Transaction rolled back: %d of %d files failed", failed, len(staged))
+		w.report(results)
+		if ctxErr != nil {
+			return results, &CancelError{Started: true, Total: len(staged), Err: ctxErr}
+		}
+		return results, fmt.Errorf("transaction rolled back: %d of %d files failed", failed, len(staged))
+	}
+
+	// Commit
+	for _, file := range staged {
+		if err := os.Rename(file.temp, file.target); err != nil {
+			os.Remove(file.temp)
+			commitErr := fmt.Errorf("error committing file %s: %v", file.target, err)
+			results.addFailure(file.target, commitErr)
+			results.Info[file.target] = commitErr.Error()
+			continue
+		}
+		results.Success++
+		results.BytesWritten += uint64(len(message))
+		if w.compress {
+			results.CompressedBytes += uint64(file.size)
+		}
+	}
+
+	// Pooled connections point to the replaced files
+	if err := w.closeAllConns(); err != nil {
+		Debug("Error closing connections after commit: %v", err)
+	}
+
+	results.SuccessRate = float64(results.Success) / float64(results.Total)
+	results.FailureRate = float64(results.Failure) / float64(results.Total)
+	w.report(results)
+	return results, nil
+}
+
+// stageFile writes the message, compressed if enabled, to a synced temp file in the
+// target's directory.
+func (w *Writer) stageFile(file *os.File, message string) stagedFile {
+	if file == nil {
+		return stagedFile{target: "nil_file", err: fmt.Errorf("nil file pointer received")}
+	}
+	target := file.Name()
+	staged := stagedFile{target: target}
+
+	temp, err := createStagingFile(target)
+	if err != nil {
+		staged.err = fmt.Errorf("error creating temp file for %s: %v", target, err)
+		return staged
+	}
+	staged.temp = temp.Name()
+
+	// Keep the target's permissions, or force those of a created file
+	if info, statErr := os.Stat(target); statErr == nil {
+		err = temp.Chmod(info.Mode().Perm())
+	} else if w.forcePerm {
+		err = temp.Chmod(filePerm)
+	}
+	if err != nil {
+		temp.Close()
+		staged.err = fmt.Errorf("error setting permissions for %s: %v", target, err)
+		return staged
+	}
+
+	if w.compress {
+		staged.size, err = w.writeCompressed(temp, message)
+	} else {
+		staged.size, err = temp.WriteString(message)
+	}
+	if err == nil {
+		err = temp.Sync()
+	}
+	closeErr := temp.Close()
+	if err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		staged.err = fmt.Errorf("error writing temp file for %s: %v", target, err)
+	}
+	return staged
+}
+
+// createStagingFile creates a new hidden temp file next to target. It is created
+// with filePerm, like the files opened by the Writer, so the umask applies to it the
+// same way instead of the 0600 of os.CreateTemp.
+func createStagingFile(target string) (*os.File, error) {
+	dir, base := filepath.Split(target)
+	for i := 0; i < 10000; i++ {
+		name := filepath.Join(dir, "."+base+".tmp-"+strconv.FormatUint(rand.Uint64(), 36))
+		file, err := os.OpenFile(name, os.O_RDWR|os.O_CREATE|os.O_EXCL, filePerm)
+		if os.IsExist(err) {
+			continue
+		}
+		return file, err
+	}
+	return nil, fmt.Errorf("could not find an unused temp file name for %s", target)
+}
+
+// ----------------------------------------------------
+// End of File
+// ----------------------------------------------------
@@ -0,0 +1,242 @@
+package writer
+
+// Write-Ahead Log for the Writer Component
+// ----------------------------------------------------
+
+import (
+	"bytes"
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync/atomic"
+	"time"
+)
+
+// ----------------------------------------------------
+// Vars
+// ----------------------------------------------------
+
+// Extension of WAL entry files
+var walExtension = ".wal"
+
+// Sequence keeping WAL entries recorded in the same nanosecond ordered
+var walSeq int64
+
+// ----------------------------------------------------
+// Structs
+// ----------------------------------------------------
+
+// walEntry struct -> one intended write, stored as JSON in the WAL directory
+type walEntry struct {
+	Time    int64  `json:"time"`    // Unix nano time the write was recorded
+	Seq     int64  `json:"seq"`     // Sequence number to order entries
+	File    string `json:"file"`    // Target file name
+	Mode    string `json:"mode"`    // Mode used for the write - a or w
+	Message string `json:"message"` // Message to write, when written as is
+	Data    []byte `json:"data"`    // Bytes to write when the message is encoded (e.g. compressed)
+}
+
+// ----------------------------------------------------
+// WAL Methods
+// ----------------------------------------------------
+
+// SetWAL enables the write-ahead log in the given directory, creating it if needed.
+// While enabled, every write records its target file, mode and message in the WAL
+// before touching the target, and removes the entry once the write is applied.
+// Entries left behind by a crash or a failed write can be replayed with RecoverWAL.
+// Pass an empty string to disable the WAL.
+func (w *Writer) SetWAL(dir string) error {
+	w.opLock.Lock()
+	defer w.opLock.Unlock()
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if dir == "" {
+		w.walDir = ""
+		return nil
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("error creating WAL directory %s: %v", dir, err)
+	}
+	w.walDir = dir
+	return nil
+}
+
+// GetWAL returns the directory of the write-ahead log, or an empty string if disabled.
+func (w *Writer) GetWAL() string {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	return w.walDir
+}
+
+// walRecord durably stores an entry for the intended write in the WAL directory and
+// returns the path of the entry. When compression is enabled, the entry holds the
+// compressed bytes written to the file rather than the message.
+func (w *Writer) walRecord(fileName string, message string) (string, error) {
+	entry := walEntry{
+		Time: time.Now().UnixNano(),
+		Seq:  atomic.AddInt64(&walSeq, 1),
+		File: fileName,
+		Mode: *w.mode.mode,
+	}
+	if w.compress {
+		var encoded bytes.Buffer
+		if _, err := w.writeCompressed(&encoded, message); err != nil {
+			return "", fmt.Errorf("error encoding WAL entry for file %s: %v", fileName, err)
+		}
+		entry.Data = encoded.Bytes()
+	} else {
+		entry.Message = message
+	}
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return "", fmt.Errorf("error encoding WAL entry for file %s: %v", fileName, err)
+	}
+
+	walFile, err := os.CreateTemp(w.walDir, "entry-*"+walExtension)
+	if err != nil {
+		return "", fmt.Errorf("error creating WAL entry for file %s: %v", fileName, err)
+	}
+	if _, err = walFile.Write(data); err == nil {
+		err = walFile.Sync()
+	}
+	closeErr := walFile.Close()
+	if err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(walFile.Name())
+		return "", fmt.Errorf("error writing WAL entry for file %s: %v", fileName, err)
+	}
+
+	Debug("WAL entry %s recorded for file %s", walFile.Name(), fileName)
+	return walFile.Name(), nil
+}
+
+// walApplied removes the WAL entry of a write that was applied.
+func (w *Writer) walApplied(entryPath string) {
+	if err := os.Remove(entryPath); err != nil {
+		Debug("Error removing applied WAL entry %s: %v", entryPath, err)
+	}
+}
+
+// RecoverWAL replays the writes left un-applied in the WAL directory, for example
+// after a crash. Entries are replayed in the order they were recorded, each with the
+// mode it was recorded with and the bytes it was to write (compressed, if it was
+// recorded with compression), and removed once applied. Files are opened like the
+// Writer's own, honoring SetForcePerm and SetDeferTruncate. Entries that cannot be
+// read or applied are kept and recorded as failures, so a later RecoverWAL can retry
+// them.
+//
+// An entry is removed only after its write is applied, so a crash between the two
+// leaves an entry whose write already reached the file. Replaying it is harmless
+// in 'w' mode, but in 'a' mode it appends the record a second time.
+//
+// It returns a Results struct with one entry per replayed write, and an error if the
+// WAL is not enabled or its directory cannot be read.
+func (w *Writer) RecoverWAL() (*Results, error) {
+	w.opLock.Lock()
+	defer w.opLock.Unlock()
+
+	if w.walDir == "" {
+		return nil, fmt.Errorf("WAL is not enabled")
+	}
+
+	paths, err := filepath.Glob(filepath.Join(w.walDir, "*"+walExtension))
+	if err != nil {
+		return nil, fmt.Errorf("error reading WAL directory %s: %v", w.walDir, err)
+	}
+
+	results := NewResults()
+
+	// Load entries
+	type loadedEntry struct {
+		path  string
+		entry walEntry
+	}
+	entries := make([]loadedEntry, 0, len(paths))
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err == nil {
+			var entry walEntry
+			if err = json.Unmarshal(data, &entry); err == nil {
+				entries = append(entries, loadedEntry{path: path, entry: entry})
+				continue
+			}
+		}
+		results.addFailure(path, fmt.Errorf("error reading WAL entry %s: %v", path, err))
+		results.Info[path] = err.Error()
+	}
+
+	// Replay in recorded order
+	slices.SortFunc(entries, func(a, b loadedEntry) int {
+		if c := cmp.Compare(a.entry.Time, b.entry.Time); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.entry.Seq, b.entry.Seq)
+	})
+
+	for _, loaded := range entries {
+		err := w.applyWALEntry(loaded.entry)
+		if err != nil {
+			results.addFailure(loaded.entry.File, err)
+			results.Info[loaded.entry.File] = err.Error()
+			continue
+		}
+		w.walApplied(loaded.path)
+		results.Success++
+		Debug("WAL entry %s replayed to file %s", loaded.path, loaded.entry.File)
+	}
+
+	// Set total and rates
+	results.Total = uint64(len(paths))
+	if results.Total > 0 {
+		results.SuccessRate = float64(results.Success) / float64(results.Total)
+		results.FailureRate = float64(results.Failure) / float64(results.Total)
+	}
+
+	return results, nil
+}
+
+// applyWALEntry writes the bytes of a WAL entry to its target file.
+func (w *Writer) applyWALEntry(entry walEntry) error {
+	fileMode, err := getFileMode(entry.Mode)
+	if err != nil {
+		return err
+	}
+
+	file, err := w.openFile(entry.File, fileMode)
+	if err != nil {
+		return fmt.Errorf("error opening file %s: %v", entry.File, err)
+	}
+
+	// Deferred or lazy truncation -> openFile dropped O_TRUNC
+	if entry.Mode == "w" && (w.deferTrunc || w.lazyTruncate) {
+		err = file.Truncate(0)
+	}
+	if err == nil {
+		if entry.Data != nil {
+			_, err = file.Write(entry.Data)
+		} else {
+			_, err = file.WriteString(entry.Message)
+		}
+	}
+	if err == nil {
+		err = file.Sync()
+	}
+	closeErr := file.Close()
+	if err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("error replaying WAL entry to file %s: %v", entry.File, err)
+	}
+	return nil
+}
+
+// ----------------------------------------------------
+// End of File
+// ----------------------------------------------------
@@ -30,16 +30,26 @@ package writer
 
 import (
 	"bufio"
+	"bytes"
+	"cmp"
+	"compress/gzip"
+	"compress/zlib"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
+	"io"
 	"log"
 	"os"
 	"runtime"
 	"slices"
+	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
+	"unicode"
 )
 
 // ----------------------------------------------------
@@ -67,6 +77,32 @@ func SetDebugMode(enabled bool) {
 	debugMode = enabled
 }
 
+// Color flag to control ANSI colors in Results.Print -> on by default when stdout is a terminal
+var colorOutput bool = isTerminal(os.Stdout)
+
+// IsColorOutput returns whether Results.Print uses ANSI colors
+func IsColorOutput() bool {
+	return colorOutput
+}
+
+// SetColorOutput enables or disables ANSI colors in Results.Print, overriding the
+// terminal auto-detection done at startup
+func SetColorOutput(enabled bool) {
+	colorOutput = enabled
+}
+
+// Permission requested for files created by the Writer
+const filePerm os.FileMode = 0666
+
+// isTerminal reports whether the file is a character device, such as a terminal
+func isTerminal(file *os.File) bool {
+	info, err := file.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}
+
 // Debug logs a message only when debug mode is enabled
 func Debug(format string, v ...interface{}) {
 	if debugMode {
@@ -85,25 +121,100 @@ func GetAvailableModes() []string {
 
 var availableModes = []string{"a", "w"}
 
+// Default multiple of GOMAXPROCS capping the number of workers of a write
+var defaultWorkerFactor = 4
+
+// ANSI color codes used by Results.Print
+var (
+	colorGreen = "\033[32m"
+	colorRed   = "\033[31m"
+	colorReset = "\033[0m"
+)
+
+// Prefix of the checksum footer line
+var checksumPrefix = "# sha256:"
+
+// Pool of buffered writers reused across file writes
+var bufferedWriterPool = sync.Pool{
+	New: func() interface{} {
+		return bufio.NewWriter(nil)
+	},
+}
+
+// Pool of gzip writers reused across compressed writes
+var gzipWriterPool = sync.Pool{
+	New: func() interface{} {
+		return gzip.NewWriter(nil)
+	},
+}
+
+// Pool of byte buffers used to assemble records before writing
+var recordBufferPool = sync.Pool{
+	New: func() interface{} {
+		buffer := make([]byte, 0, 512)
+		return &buffer
+	},
+}
+
 // ----------------------------------------------------
 // Structs
 // ----------------------------------------------------
 
 // Writer struct
 type Writer struct {
-	files         *[]*os.File     // Slice of pointers to files
-	mode          *Mode           // Mode for writing - a or w
-	message       *string         // Message to write
-	openFilesPool sync.Map        // Pool of open files
-	connPoolLock  sync.RWMutex    // Lock for the connection pool
-	connLastUsed  sync.Map        // Map to track when connections were last used
-	maxConns      uint64          // Max number of connections
-	retries       uint64          // Number of retries
-	backoff       uint64          // Backoff between retries
-	ctx           context.Context // Context
-	mu            sync.RWMutex    // Mutex
+	files         *[]*os.File            // Slice of pointers to files
+	mode          *Mode                  // Mode for writing - a or w
+	message       *string                // Message to write
+	openFilesPool sync.Map               // Pool of open files
+	connPoolLock  sync.RWMutex           // Lock for the connection pool
+	connLastUsed  sync.Map               // Map to track when connections were last used
+	maxConns      uint64                 // Max number of connections
+	retries       uint64                 // Number of retries
+	backoff       uint64                 // Backoff between retries
+	ctx           context.Context        // Context
+	mu            sync.RWMutex           // Mutex
+	opLock        sync.Mutex             // Lock serializing write, files and cleanup operations
+	progressCh    chan<- Progress        // Channel receiving per-file completion events
+	lazyTruncate  bool                   // Skip 'w' mode writes when content already matches
+	afterWrite    AfterWriteFunc         // Hook invoked after each successful file write
+	continueOpen  bool                   // Collect path open errors instead of failing
+	openErrs      map[string]error       // Paths that failed to open, reported as pre-failures
+	adaptive      bool                   // Adjust the worker count during a write
+	adaptiveMin   int                    // Minimum number of adaptive workers
+	adaptiveMax   int                    // Maximum number of adaptive workers
+	activeWorkers int64                  // Workers currently allowed to take jobs
+	newline       NewlineStyle           // Line ending used by line based writes
+	cancel        context.CancelFunc     // Cancel function of the running write context
+	cancelLock    sync.Mutex             // Lock for the cancel function
+	jsonIndent    string                 // Indentation used by WriteJSON
+	workerFactor  int                    // Max workers per GOMAXPROCS, 0 disables the cap
+	sortBySize    bool                   // Dispatch files largest-first
+	walDir        string                 // Directory of the write-ahead log, empty disables it
+	checksum      bool                   // Append a sha256 checksum footer to each write
+	compress      bool                   // Write gzip compressed content
+	compressDict  []byte                 // Preset dictionary, switches compression to zlib
+	dictPool      *sync.Pool             // Pool of zlib writers using the preset dictionary
+	durable       bool                   // Fsync pooled files on Checkpoint
+	forcePerm     bool                   // Chmod created files to filePerm, bypassing umask
+	retryBudget   time.Duration          // Max elapsed time of a file's retries, 0 disables it
+	poolHits      uint64                 // Connections found usable in the pool
+	poolMisses    uint64                 // Connections missing from the pool
+	poolEvictions uint64                 // Connections closed to make room in the pool
+	deferTrunc    bool                   // Open without O_TRUNC and truncate right before writing
+	reportFunc    ReportFunc             // Hook receiving the final Results of every write
+	dedupTTL      time.Duration          // Window skipping identical writes, 0 disables it
+	dedupSeen     map[[32]byte]time.Time // Time of the recent successful writes by hash
+	dedupLock     sync.Mutex             // Lock for the recent write hashes
 }
 
+// AfterWriteFunc is invoked after each successful file write. A returned error marks
+// the file as failed, even though the write itself succeeded.
+type AfterWriteFunc func(fileName string, r *Results) error
+
+// ReportFunc receives the final Results at the end of every write operation, e.g. to
+// route them to a logging or metrics system.
+type ReportFunc func(r *Results)
+
 // WriterConfig struct -> use with NewWriterFromStruct
 type WriterConfig struct {
 	Files   *[]*os.File
@@ -121,24 +232,94 @@ type Mode struct {
 
 // Results struct
 type Results struct {
-	Total       uint64                 `json:"total"`        // Total number of messages
-	ErrSlice    []*error               `json:"err_slice"`    // Slice of errors
-	Success     uint64                 `json:"success"`      // Number of successful writes
-	Failure     uint64                 `json:"failure"`      // Number of failed writes
-	SuccessRate float64                `json:"success_rate"` // Percentage of successful writes
-	FailureRate float64                `json:"failure_rate"` // Percentage of failed writes
-	Info        map[string]interface{} `json:"info"`         // Map of additional information
-	mu          sync.RWMutex           // Mutex
+	Total           uint64                 `json:"total"`            // Total number of messages
+	ErrSlice        []*error               `json:"err_slice"`        // Slice of errors
+	Success         uint64                 `json:"success"`          // Number of successful writes
+	Failure         uint64                 `json:"failure"`          // Number of failed writes
+	SuccessRate     float64                `json:"success_rate"`     // Percentage of successful writes
+	FailureRate     float64                `json:"failure_rate"`     // Percentage of failed writes
+	BytesWritten    uint64                 `json:"bytes_written"`    // Uncompressed bytes of the successful writes, checksum footers included
+	Deduped         uint64                 `json:"deduped"`          // Number of writes skipped as duplicates
+	CompressedBytes uint64                 `json:"compressed_bytes"` // Bytes written to files when compression is enabled
+	Info            map[string]interface{} `json:"info"`             // Map of additional information
+	fileErrs        map[string]error       // Map of errors by file name
+	mu              sync.RWMutex           // Mutex
+}
+
+// NewlineStyle represents the line ending used by line based writes
+type NewlineStyle int
+
+const (
+	NewlineLF   NewlineStyle = iota // Unix line ending -> "\n" (default)
+	NewlineCRLF                     // Windows line ending -> "\r\n"
+)
+
+// String returns the line ending of the NewlineStyle.
+func (n NewlineStyle) String() string {
+	if n == NewlineCRLF {
+		return "\r\n"
+	}
+	return "\n"
+}
+
+// Progress struct -> sent on the progress channel after each file is processed
+type Progress struct {
+	FileName  string // Name of the processed file
+	Completed int    // Number of files processed so far in the current write
+	Total     int    // Total number of files targeted by the current write, see TotalTargets
+	Err       error  // Error for the processed file, nil on success
+}
+
+// CancelError is returned when a write operation is interrupted by its context
+// being canceled or reaching its deadline. It tells a write that never started
+// apart from one interrupted mid-batch, and how many files were written before.
+// It unwraps to the context error, so errors.Is(err, context.DeadlineExceeded) works.
+type CancelError struct {
+	Started   bool  // Whether files were dispatched before the cancellation
+	Completed int   // Number of files written successfully before the cancellation
+	Total     int   // Total number of files targeted by the write
+	Err       error // Context error
+}
+
+// Error returns the cancellation reason with the partial progress.
+func (e *CancelError) Error() string {
+	if !e.Started {
+		return fmt.Sprintf("write canceled before starting: %v", e.Err)
+	}
+	return fmt.Sprintf("write canceled after %d of %d files completed: %v", e.Completed, e.Total, e.Err)
+}
+
+// Unwrap returns the context error.
+func (e *CancelError) Unwrap() error {
+	return e.Err
+}
+
+// PoolStats struct -> connection pool usage counters, see Writer.PoolStats
+type PoolStats struct {
+	Hits      uint64 // Connections reused from the pool
+	Misses    uint64 // Connections missing from the pool, stored anew
+	Evictions uint64 // Least recently used connections closed because the pool was full
+}
+
+// HitRatio returns the share of connection lookups served by the pool, or 0 when
+// there were none. A low ratio with many evictions signals an undersized pool.
+func (p PoolStats) HitRatio() float64 {
+	lookups := p.Hits + p.Misses
+	if lookups == 0 {
+		return 0
+	}
+	return float64(p.Hits) / float64(lookups)
 }
 
 // struct for JSON unmarshaling
 type jsonConfig struct {
-	Files   []string `json:"files"`   // Array of file paths
-	Mode    string   `json:"mode"`    // Mode as string
-	Message string   `json:"message"` // Message as string
-	MaxPool uint64   `json:"maxPool"` // Max pool size
-	Retries uint64   `json:"retries"` // Number of retries
-	Backoff uint64   `json:"backoff"` // Backoff duration
+	Files               []string `json:"files"`               // Array of file paths
+	Mode                string   `json:"mode"`                // Mode as string
+	Message             string   `json:"message"`             // Message as string
+	MaxPool             uint64   `json:"maxPool"`             // Max pool size
+	Retries             uint64   `json:"retries"`             // Number of retries
+	Backoff             uint64   `json:"backoff"`             // Backoff duration
+	ContinueOnOpenError bool     `json:"continueOnOpenError"` // Collect open errors instead of failing
 }
 
 // ----------------------------------------------------
@@ -174,36 +355,52 @@ func (w *Writer) fullWriteCheck() error {
 
 // GetFiles returns a pointer to the Writer's files slice of pointers to os.File.
 func (w *Writer) GetFiles() *[]*os.File {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
 	return w.files
 }
 
 // GetMode returns a pointer to the Writer's mode struct.
 func (w *Writer) GetMode() *Mode {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
 	return w.mode
 }
 
 // GetMessage returns a pointer to the Writer's message string.
 func (w *Writer) GetMessage() *string {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
 	return w.message
 }
 
 // GetRetries returns the Writer's number of retries.
 func (w *Writer) GetRetries() uint64 {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
 	return w.retries
 }
 
 // GetBackoff returns the Writer's backoff value.
 func (w *Writer) GetBackoff() uint64 {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
 	return w.backoff
 }
 
 // GetMaxPool returns the Writer's maximum number of connections.
 func (w *Writer) GetMaxPool() uint64 {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
 	return w.maxConns
 }
 
 // SetFiles sets the Writer's files slice of pointers to os.File.
+// It waits for any in-flight write operation to finish.
 func (w *Writer) SetFiles(files *[]*os.File) error {
+	w.opLock.Lock()
+	defer w.opLock.Unlock()
+
 	err := w.fullWriteCheck()
 	if err != nil {
 		return err
@@ -213,26 +410,100 @@ func (w *Writer) SetFiles(files *[]*os.File) error {
 		logger.Print("Files is nil")
 		return fmt.Errorf("files is nil")
 	}
-	w.files = files
+	w.mu.Lock()
+	w.files = normalizeFiles(files)
+	w.openErrs = nil
+	w.mu.Unlock()
+	return nil
+}
+
+// SetFilesFromPaths opens the given paths using the Writer's mode flags and replaces
+// the Writer's files slice with them. Previously pooled connections are closed first.
+//
+// If any path cannot be opened, the files already opened are closed and an error is
+// returned, leaving the Writer's files unchanged. When SetContinueOnOpenError is
+// enabled, invalid paths are collected instead and reported as failures by
+// subsequent writes.
+func (w *Writer) SetFilesFromPaths(paths []string) error {
+	w.opLock.Lock()
+	defer w.opLock.Unlock()
+
+	err := w.fullWriteCheck()
+	if err != nil {
+		return err
+	}
+
+	fileMode, err := getFileMode(*w.mode.mode)
+	if err != nil {
+		return err
+	}
+
+	// Open paths
+	files := make([]*os.File, 0, len(paths))
+	openErrs := make(map[string]error)
+	for _, path := range paths {
+		file, err := w.openFile(path, fileMode)
+		if err != nil {
+			if w.continueOpen {
+				Debug("Failed to open file %s, continuing: %v", path, err)
+				openErrs[path] = fmt.Errorf("failed to open file %s: %v", path, err)
+				continue
+			}
+			// Close all previously opened files
+			for _, f := range files {
+				f.Close()
+			}
+			return fmt.Errorf("failed to open file %s: %v", path, err)
+		}
+		files = append(files, file)
+	}
+
+	// Close previously pooled connections
+	err = w.closeAllConns()
+	if err != nil {
+		for _, f := range files {
+			f.Close()
+		}
+		return err
+	}
+
+	w.mu.Lock()
+	w.files = &files
+	w.openErrs = nil
+	if len(openErrs) > 0 {
+		w.openErrs = openErrs
+	}
+	w.mu.Unlock()
 	return nil
 }
 
 // AddFiles appends the given files to the Writer's existing files slice,
 // and sets the Writer's files field to the new slice.
 // It returns an error if the Writer's fullWriteCheck fails.
+// It waits for any in-flight write operation to finish.
 func (w *Writer) AddFiles(files []*os.File) error {
+	w.opLock.Lock()
+	defer w.opLock.Unlock()
+
 	err := w.fullWriteCheck()
 	if err != nil {
 		return err
 	}
 	copy := *w.files
 	copy = append(copy, files...)
+	w.mu.Lock()
 	w.files = &copy
+	w.mu.Unlock()
 	return nil
 }
 
 // SetMode sets the Writer's mode struct.
 func (w *Writer) SetMode(mode *Mode) error {
+	w.opLock.Lock()
+	defer w.opLock.Unlock()
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
 	err := w.fullWriteCheck()
 	if err != nil {
 		return err
@@ -247,6 +518,11 @@ func (w *Writer) SetMode(mode *Mode) error {
 
 // SetMessage sets the Writer's message string.
 func (w *Writer) SetMessage(message *string) error {
+	w.opLock.Lock()
+	defer w.opLock.Unlock()
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
 	err := w.fullWriteCheck()
 	if err != nil {
 		return err
@@ -261,6 +537,11 @@ func (w *Writer) SetMessage(message *string) error {
 
 // SetRetries sets the Writer's number of retries.
 func (w *Writer) SetRetries(retries uint64) error {
+	w.opLock.Lock()
+	defer w.opLock.Unlock()
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
 	err := w.fullWriteCheck()
 	if err != nil {
 		return err
@@ -271,6 +552,11 @@ func (w *Writer) SetRetries(retries uint64) error {
 
 // SetBackoff sets the Writer's backoff value.
 func (w *Writer) SetBackoff(backoff uint64) error {
+	w.opLock.Lock()
+	defer w.opLock.Unlock()
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
 	err := w.fullWriteCheck()
 	if err != nil {
 		return err
@@ -281,6 +567,11 @@ func (w *Writer) SetBackoff(backoff uint64) error {
 
 // SetMaxPool sets the Writer's maximum number of connections in the openFilesPool.
 func (w *Writer) SetMaxPool(maxPool uint64) error {
+	w.opLock.Lock()
+	defer w.opLock.Unlock()
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
 	err := w.fullWriteCheck()
 	if err != nil {
 		return err
@@ -289,8 +580,418 @@ func (w *Writer) SetMaxPool(maxPool uint64) error {
 	return nil
 }
 
-// SetContext sets the Writer's context.
+// TotalTargets returns the number of files the next write operation will target,
+// including the paths that failed to open (see SetContinueOnOpenError), which are
+// reported as failures. Together with SetProgressChannel it forms the progress-bar
+// contract: read TotalTargets up front, then drain the progress channel until
+// Completed reaches it.
+func (w *Writer) TotalTargets() int {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	if w.files == nil {
+		return len(w.openErrs)
+	}
+	return len(*w.files) + len(w.openErrs)
+}
+
+// SetProgressChannel sets the channel receiving a Progress event after each file
+// is processed by a write operation. Events are sent synchronously, so the caller
+// must keep draining the channel while a write is running. Pass nil to disable
+// progress events.
+func (w *Writer) SetProgressChannel(ch chan<- Progress) {
+	w.opLock.Lock()
+	defer w.opLock.Unlock()
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.progressCh = ch
+}
+
+// SetLazyTruncate enables or disables lazy truncation. When enabled, in 'w' mode
+// the Writer reads the current content of each file first and only truncates and
+// writes it if the content differs from the bytes it would write (the compressed
+// message when compression is enabled). Unchanged files are left untouched,
+// preserving their modification time. Files opened by the Writer afterwards (e.g.
+// by SetFilesFromPaths) are opened without O_TRUNC, so their content survives until
+// it is compared. It has no effect in 'a' mode.
+func (w *Writer) SetLazyTruncate(enabled bool) {
+	w.opLock.Lock()
+	defer w.opLock.Unlock()
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.lazyTruncate = enabled
+}
+
+// IsLazyTruncate returns whether lazy truncation is enabled.
+func (w *Writer) IsLazyTruncate() bool {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	return w.lazyTruncate
+}
+
+// SetAfterWrite sets a hook invoked after each file's successful write, once retries
+// are done. If the hook returns an error the file is recorded as a failure, which
+// lets side effects (e.g. a downstream notification) be composed with the write.
+// The hook runs while the write still holds the Writer, so it may call the Writer's
+// getters but must not call its write or configuration methods, which would wait
+// for the write to finish. Pass nil to remove the hook.
+func (w *Writer) SetAfterWrite(hook AfterWriteFunc) {
+	w.opLock.Lock()
+	defer w.opLock.Unlock()
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.afterWrite = hook
+}
+
+// SetReportFunc sets a hook invoked with the final Results at the end of every write
+// operation (Write, WriteInto, WriteWithTimeout, StartWriteWithCancel, WriteJSON,
+// WriteLogfmt, WriteLineDelta and WriteTransactional), including writes that failed
+// or were canceled. A write rejected before writing any file (no files, duplicate
+// names or a context already done) is reported with every file recorded as failed;
+// configuration errors returned before that, such as a nil message or the wrong
+// mode, are not. Through WriteInto, the hook receives the Results of that write
+// only, not the accumulated ones. It lets fire-and-forget callers, or users of a
+// shared Writer such as Dwriter, route results to a logging or metrics system
+// without capturing the return value. The hook runs on the writing goroutine
+// while the operation still holds the Writer, so it must not call the Writer's
+// write or configuration methods. Pass nil to remove it.
+func (w *Writer) SetReportFunc(fn ReportFunc) {
+	w.opLock.Lock()
+	defer w.opLock.Unlock()
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.reportFunc = fn
+}
+
+// SetContinueOnOpenError enables or disables collecting open errors for path-based
+// file setup with SetFilesFromPaths (NewWriterFromJSON uses its continueOnOpenError
+// field instead). When enabled, paths that cannot be opened do not abort the operation;
+// they are kept and reported as failures by every subsequent write, while the valid
+// paths are written normally. If no path could be opened, writes return Results
+// holding only these failures. Setting new files clears the collected errors.
+func (w *Writer) SetContinueOnOpenError(enabled bool) {
+	w.opLock.Lock()
+	defer w.opLock.Unlock()
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.continueOpen = enabled
+}
+
+// GetOpenErrors returns a copy of the open errors collected for invalid paths,
+// keyed by path.
+func (w *Writer) GetOpenErrors() map[string]error {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+
+	openErrs := make(map[string]error, len(w.openErrs))
+	for path, err := range w.openErrs {
+		openErrs[path] = err
+	}
+	return openErrs
+}
+
+// SetAdaptiveWorkers enables or disables adaptive worker scaling. When enabled, a
+// write starts with the minimum number of workers and, after each round of writes,
+// adds a worker while writes stay fast and error free, or removes one when errors
+// occur or the average write latency rises. Bounds are set with SetAdaptiveWorkerBounds.
+func (w *Writer) SetAdaptiveWorkers(enabled bool) {
+	w.opLock.Lock()
+	defer w.opLock.Unlock()
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.adaptive = enabled
+}
+
+// SetAdaptiveWorkerBounds sets the minimum and maximum number of workers used by
+// adaptive worker scaling. A maxWorkers of 0 uses the maxWorkers value given to the
+// write. It returns an error if minWorkers is lower than 1 or greater than a non-zero
+// maxWorkers.
+func (w *Writer) SetAdaptiveWorkerBounds(minWorkers int, maxWorkers int) error {
+	if minWorkers < 1 {
+		return fmt.Errorf("min workers must be at least 1, got %d", minWorkers)
+	}
+	if maxWorkers != 0 && minWorkers > maxWorkers {
+		return fmt.Errorf("min workers %d is greater than max workers %d", minWorkers, maxWorkers)
+	}
+	w.opLock.Lock()
+	defer w.opLock.Unlock()
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.adaptiveMin = minWorkers
+	w.adaptiveMax = maxWorkers
+	return nil
+}
+
+// ActiveWorkers returns the number of workers currently allowed to take jobs in a
+// running write, or 0 when no write is running.
+func (w *Writer) ActiveWorkers() int {
+	return int(atomic.LoadInt64(&w.activeWorkers))
+}
+
+// SetNewlineStyle sets the line ending (NewlineLF or NewlineCRLF) used by line based
+// writes, such as WriteLogfmt. The default is NewlineLF.
+func (w *Writer) SetNewlineStyle(style NewlineStyle) error {
+	if style != NewlineLF && style != NewlineCRLF {
+		return fmt.Errorf("invalid newline style: %d", style)
+	}
+	w.opLock.Lock()
+	defer w.opLock.Unlock()
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.newline = style
+	return nil
+}
+
+// GetNewlineStyle returns the line ending used by line based writes.
+func (w *Writer) GetNewlineStyle() NewlineStyle {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	return w.newline
+}
+
+// Cancel cancels the write operation currently running on the Writer, if any.
+// Each write derives a cancelable context from the Writer's context, so any holder
+// of the Writer can abort a running write without setting up the context itself.
+// Files not yet written when Cancel is called are recorded as failures.
+func (w *Writer) Cancel() {
+	w.cancelLock.Lock()
+	defer w.cancelLock.Unlock()
+	if w.cancel != nil {
+		w.cancel()
+	}
+}
+
+// SetJSONIndent sets the indentation used by WriteJSON, e.g. "  " or "\t".
+// An empty string, the default, writes compact JSON.
+func (w *Writer) SetJSONIndent(indent string) {
+	w.opLock.Lock()
+	defer w.opLock.Unlock()
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.jsonIndent = indent
+}
+
+// SetWorkerCapFactor sets the multiple of GOMAXPROCS that caps the number of workers
+// of a write. Workers beyond that mostly wait on I/O and contend on the connection
+// pool, so the cap saves memory without hurting throughput. The default is 4, and a
+// factor of 0 disables the cap. It returns an error if factor is negative.
+func (w *Writer) SetWorkerCapFactor(factor int) error {
+	if factor < 0 {
+		return fmt.Errorf("worker cap factor must not be negative, got %d", factor)
+	}
+	w.opLock.Lock()
+	defer w.opLock.Unlock()
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.workerFactor = factor
+	return nil
+}
+
+// GetWorkerCapFactor returns the multiple of GOMAXPROCS capping the number of workers.
+func (w *Writer) GetWorkerCapFactor() int {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	return w.workerFactor
+}
+
+// SetSortBySize enables or disables dispatching files largest-first. Each file is
+// stat'ed once at the start of a write, and the largest files are handed to the
+// workers first (longest-processing-time heuristic), which reduces tail latency
+// with a fixed worker pool when file sizes are mixed.
+func (w *Writer) SetSortBySize(enabled bool) {
+	w.opLock.Lock()
+	defer w.opLock.Unlock()
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.sortBySize = enabled
+}
+
+// SetChecksumFooter enables or disables the checksum footer. When enabled, every
+// write appends a final line like "# sha256:<hex>" covering the content written
+// before it, so the file can later be checked with VerifyChecksumFooter. Since the
+// footer is written to the file, Results.BytesWritten includes it.
+func (w *Writer) SetChecksumFooter(enabled bool) {
+	w.opLock.Lock()
+	defer w.opLock.Unlock()
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.checksum = enabled
+}
+
+// SetCompression enables or disables gzip compression. When enabled, each write is
+// written to the files as a gzip member, so appended writes still form a valid gzip
+// stream. Results report both the uncompressed BytesWritten and the CompressedBytes
+// actually written.
+func (w *Writer) SetCompression(enabled bool) {
+	w.opLock.Lock()
+	defer w.opLock.Unlock()
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.compress = enabled
+}
+
+// SetCompressionDict sets a preset dictionary used when compression is enabled,
+// which improves the ratio of small records sharing content with the dictionary.
+// Since gzip cannot carry a preset dictionary, each write is then written as a zlib
+// stream instead, to be read back with zlib.NewReaderDict and the same dictionary.
+// These streams use zlib.BestCompression, the levels at which the dictionary is used.
+// Pass nil to go back to gzip.
+func (w *Writer) SetCompressionDict(dict []byte) {
+	w.opLock.Lock()
+	defer w.opLock.Unlock()
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if len(dict) == 0 {
+		w.compressDict = nil
+		w.dictPool = nil
+		return
+	}
+	compressDict := slices.Clone(dict)
+	w.compressDict = compressDict
+	w.dictPool = &sync.Pool{
+		New: func() interface{} {
+			// Below level 7, flate does not match against a preset dictionary.
+			// The level is valid, so no error is possible
+			zlibWriter, _ := zlib.NewWriterLevelDict(nil, zlib.BestCompression, compressDict)
+			return zlibWriter
+		},
+	}
+}
+
+// GetCompressionDict returns a copy of the preset compression dictionary.
+func (w *Writer) GetCompressionDict() []byte {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	return slices.Clone(w.compressDict)
+}
+
+// SetWriteDedupTTL sets the window in which identical writes are skipped. A write of
+// the same message to the same file within ttl of the last successful one is not
+// performed; it counts as a success, is recorded in Results.Deduped and its Info
+// entry is set to "deduped". Pass 0 to disable deduplication and forget recent writes.
+func (w *Writer) SetWriteDedupTTL(ttl time.Duration) {
+	w.opLock.Lock()
+	defer w.opLock.Unlock()
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.dedupLock.Lock()
+	defer w.dedupLock.Unlock()
+	if ttl < 0 {
+		ttl = 0
+	}
+	w.dedupTTL = ttl
+	w.dedupSeen = nil
+}
+
+// GetWriteDedupTTL returns the write deduplication window.
+func (w *Writer) GetWriteDedupTTL() time.Duration {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	return w.dedupTTL
+}
+
+// SetDeferTruncate enables or disables deferred truncation. By default, 'w' mode
+// opens files with O_TRUNC, so a file is emptied as soon as it is opened (e.g. by
+// SetFilesFromPaths) even if the write then fails, for instance because the
+// message cannot be generated. When enabled, files are opened without O_TRUNC and
+// each file is truncated right before its message is written. It has no effect
+// in 'a' mode.
+func (w *Writer) SetDeferTruncate(enabled bool) {
+	w.opLock.Lock()
+	defer w.opLock.Unlock()
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.deferTrunc = enabled
+}
+
+// IsDeferTruncate returns whether truncation is deferred until the write.
+func (w *Writer) IsDeferTruncate() bool {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	return w.deferTrunc
+}
+
+// SetMaxRetryDuration sets the time budget for the retries of each file. Once the
+// attempts and backoffs of a file have taken longer than d, it is not retried again
+// even if retries remain, which bounds the worst-case latency per file. Pass 0 to
+// only bound retries by count.
+func (w *Writer) SetMaxRetryDuration(d time.Duration) {
+	w.opLock.Lock()
+	defer w.opLock.Unlock()
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if d < 0 {
+		d = 0
+	}
+	w.retryBudget = d
+}
+
+// GetMaxRetryDuration returns the time budget for the retries of each file.
+func (w *Writer) GetMaxRetryDuration() time.Duration {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	return w.retryBudget
+}
+
+// SetForcePerm enables or disables forcing the permissions of created files. On Unix
+// the requested 0666 permission is masked by the process umask; when enabled, files
+// created by the Writer are chmod'ed to exactly 0666 right after creation. Existing
+// files keep their permissions.
+//
+// The chmod is not atomic with the creation: the file briefly exists with the umask
+// applied, and a file created by another process between the existence check and
+// the open is not chmod'ed. Since 0666 makes files world-writable, only enable it
+// in directories where that is intended.
+func (w *Writer) SetForcePerm(enabled bool) {
+	w.opLock.Lock()
+	defer w.opLock.Unlock()
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.forcePerm = enabled
+}
+
+// IsForcePerm returns whether created files are chmod'ed to bypass the umask.
+func (w *Writer) IsForcePerm() bool {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	return w.forcePerm
+}
+
+// openFile opens the named file with the given flags and filePerm. When forcePerm is
+// enabled and the file did not exist, it is chmod'ed to filePerm after creation.
+// When deferTrunc or lazyTruncate is enabled, O_TRUNC is dropped from the flags.
+func (w *Writer) openFile(name string, flag int) (*os.File, error) {
+	// Deferred or lazy truncation -> truncated by writeToFile once the message is ready
+	if w.deferTrunc || w.lazyTruncate {
+		flag &^= os.O_TRUNC
+	}
+
+	created := false
+	if w.forcePerm && flag&os.O_CREATE != 0 {
+		_, statErr := os.Stat(name)
+		created = os.IsNotExist(statErr)
+	}
+
+	file, err := os.OpenFile(name, flag, filePerm)
+	if err != nil {
+		return nil, err
+	}
+
+	if created {
+		if err := file.Chmod(filePerm); err != nil {
+			file.Close()
+			return nil, fmt.Errorf("error setting permissions for %s: %v", name, err)
+		}
+	}
+	return file, nil
+}
+
+// SetContext sets the Writer's context. It waits for any running operation, since
+// each write derives its own context from the Writer's context when it starts.
 func (w *Writer) SetContext(ctx context.Context) {
+	w.opLock.Lock()
+	defer w.opLock.Unlock()
+	w.mu.Lock()
+	defer w.mu.Unlock()
 	w.ctx = ctx
 }
 
@@ -321,11 +1022,8 @@ func NewWriter(
 	retries uint64,
 	backoff uint64,
 ) *Writer {
-	if files == nil {
-		files = &[]*os.File{}
-	}
 	return &Writer{
-		files:         files,
+		files:         normalizeFiles(files),
 		mode:          mode,
 		message:       message,
 		openFilesPool: sync.Map{},
@@ -336,12 +1034,26 @@ func NewWriter(
 		ctx:           context.Background(),
 		mu:            sync.RWMutex{},
 		connPoolLock:  sync.RWMutex{},
+		opLock:        sync.Mutex{},
+		workerFactor:  defaultWorkerFactor,
+		durable:       true,
 	}
 }
 
 // Config
 // ----------------------------------------------------
 
+// normalizeFiles makes sure the Writer never holds a nil files pointer or a
+// pointer to a nil slice. Both cases are replaced by a pointer to an empty
+// slice, so every path treats them exactly like an empty files slice.
+func normalizeFiles(files *[]*os.File) *[]*os.File {
+	if files == nil || *files == nil {
+		emptySlice := make([]*os.File, 0)
+		return &emptySlice
+	}
+	return files
+}
+
 // Helper to validated Map
 func validateMap(config map[string]interface{}) error {
 	if config == nil {
@@ -423,7 +1135,7 @@ func NewWriterFromMap(config map[string]interface{}) (*Writer, error) {
 	}
 
 	return &Writer{
-		files:         config["files"].(*[]*os.File),
+		files:         normalizeFiles(config["files"].(*[]*os.File)),
 		mode:          config["mode"].(*Mode),
 		message:       config["message"].(*string),
 		retries:       config["retries"].(uint64),
@@ -434,6 +1146,9 @@ func NewWriterFromMap(config map[string]interface{}) (*Writer, error) {
 		maxConns:      config["maxPool"].(uint64),
 		ctx:           context.Background(),
 		mu:            sync.RWMutex{},
+		opLock:        sync.Mutex{},
+		workerFactor:  defaultWorkerFactor,
+		durable:       true,
 	}, nil
 
 }
@@ -446,6 +1161,8 @@ func NewWriterFromMap(config map[string]interface{}) (*Writer, error) {
 //   - "maxPool": max connections
 //   - "retries": number of retries
 //   - "backoff": backoff duration in ms
+//   - "continueOnOpenError": optional, when true paths that cannot be opened are
+//     collected as failures for subsequent writes instead of failing construction
 //
 // Example JSON:
 //
@@ -455,7 +1172,8 @@ func NewWriterFromMap(config map[string]interface{}) (*Writer, error) {
 //	  "message": "Hello, World!",
 //	  "maxPool": 10,
 //	  "retries": 3,
-//	  "backoff": 100
+//	  "backoff": 100,
+//	  "continueOnOpenError": false
 //	}
 func NewWriterFromJSON(config []byte) (*Writer, error) {
 	var jc jsonConfig
@@ -465,9 +1183,16 @@ func NewWriterFromJSON(config []byte) (*Writer, error) {
 
 	// Convert file paths to *os.File slice
 	files := make([]*os.File, 0, len(jc.Files))
+	openErrs := make(map[string]error)
 	for _, path := range jc.Files {
 		file, err := os.CreateTemp("", path)
 		if err != nil {
+			// Collect as pre-failure
+			if jc.ContinueOnOpenError {
+				Debug("Failed to open file %s, continuing: %v", path, err)
+				openErrs[path] = fmt.Errorf("failed to open file %s: %v", path, err)
+				continue
+			}
 			// Close all previously opened files
 			for _, f := range files {
 				f.Close()
@@ -496,7 +1221,15 @@ func NewWriterFromJSON(config []byte) (*Writer, error) {
 		"backoff": jc.Backoff,
 	}
 
-	return NewWriterFromMap(conf)
+	w, err := NewWriterFromMap(conf)
+	if err != nil {
+		return nil, err
+	}
+	w.continueOpen = jc.ContinueOnOpenError
+	if len(openErrs) > 0 {
+		w.openErrs = openErrs
+	}
+	return w, nil
 }
 
 // NewWriterFromStruct creates a new Writer instance from a WriterConfig struct.
@@ -516,7 +1249,7 @@ func NewWriterFromStruct(config *WriterConfig) (*Writer, error) {
 	}
 	// Return Writer
 	return &Writer{
-		files:         config.Files,
+		files:         normalizeFiles(config.Files),
 		mode:          config.Mode,
 		message:       config.Message,
 		retries:       config.Retries,
@@ -527,6 +1260,9 @@ func NewWriterFromStruct(config *WriterConfig) (*Writer, error) {
 		maxConns:      config.MaxPool,
 		ctx:           context.Background(),
 		mu:            sync.RWMutex{},
+		opLock:        sync.Mutex{},
+		workerFactor:  defaultWorkerFactor,
+		durable:       true,
 	}, nil
 }
 
@@ -536,11 +1272,11 @@ func NewWriterFromStruct(config *WriterConfig) (*Writer, error) {
 // writeToFile writes a message to a file, ensuring the file is open and using the correct mode.
 // If the file is not open, it opens the file with the specified mode and updates the openFilesPool.
 // If there is an error during the writing process, it logs the error and returns it.
-func (w *Writer) writeToFile(file *os.File, message string, results *Results, mu *sync.RWMutex) error {
+func (w *Writer) writeToFile(ctx context.Context, file *os.File, message string, results *Results, mu *sync.RWMutex) error {
 	// Check if context is done
 	select {
-	case <-w.ctx.Done():
-		return w.ctx.Err()
+	case <-ctx.Done():
+		return ctx.Err()
 	default:
 	}
 
@@ -561,9 +1297,39 @@ func (w *Writer) writeToFile(file *os.File, message string, results *Results, mu
 		return err
 	}
 
+	// Deferred truncation -> truncate right before writing
+	truncate := w.deferTrunc && *w.mode.mode == "w"
+
+	// Lazy truncate -> skip files whose content already matches the bytes to write
+	var encoded []byte
+	if w.lazyTruncate && *w.mode.mode == "w" {
+		expected := []byte(message)
+		if w.compress {
+			var buffer bytes.Buffer
+			if _, err = w.writeCompressed(&buffer, message); err != nil {
+				mu.Lock()
+				defer mu.Unlock()
+				results.Info[file.Name()] = err.Error()
+				return fmt.Errorf("error compressing message for file %s: %v", file.Name(), err)
+			}
+			encoded = buffer.Bytes()
+			expected = encoded
+		}
+		current, readErr := os.ReadFile(file.Name())
+		if readErr == nil && bytes.Equal(current, expected) {
+			Debug("File %s unchanged, skipping write", file.Name())
+			mu.Lock()
+			defer mu.Unlock()
+			results.Info[file.Name()] = "unchanged"
+			return nil
+		}
+		// Content differs -> the file was not truncated at open
+		truncate = true
+	}
+
 	// Check if file is open -> if not open, open it
 	if !w.CheckConnStatus(file) {
-		newFile, err := os.OpenFile(file.Name(), fileMode, 0666)
+		newFile, err := w.openFile(file.Name(), fileMode)
 		if err != nil {
 			mu.Lock()
 			defer mu.Unlock()
@@ -588,9 +1354,34 @@ func (w *Writer) writeToFile(file *os.File, message string, results *Results, mu
 		file = newFile
 	}
 
-	// Write to file
-	bufferedWriter := bufio.NewWriter(file)
-	_, err = bufferedWriter.WriteString(message)
+	// The message is ready, so empty the file now
+	if truncate {
+		if err = file.Truncate(0); err == nil {
+			_, err = file.Seek(0, io.SeekStart)
+		}
+		if err != nil {
+			mu.Lock()
+			defer mu.Unlock()
+			results.Info[file.Name()] = err.Error()
+			return fmt.Errorf("error truncating file %s: %v", file.Name(), err)
+		}
+	}
+
+	// Write to file -> reuse a pooled buffered writer
+	bufferedWriter := bufferedWriterPool.Get().(*bufio.Writer)
+	bufferedWriter.Reset(file)
+	defer func() {
+		bufferedWriter.Reset(nil)
+		bufferedWriterPool.Put(bufferedWriter)
+	}()
+	var compressedSize int
+	if encoded != nil {
+		compressedSize, err = bufferedWriter.Write(encoded)
+	} else if w.compress {
+		compressedSize, err = w.writeCompressed(bufferedWriter, message)
+	} else {
+		_, err = bufferedWriter.WriteString(message)
+	}
 
 	// Check for error
 	if err != nil {
@@ -616,9 +1407,56 @@ func (w *Writer) writeToFile(file *os.File, message string, results *Results, mu
 		return fmt.Errorf("error flushing buffer for file %s: %v", file.Name(), err)
 	}
 
+	// Track written bytes
+	mu.Lock()
+	defer mu.Unlock()
+	results.BytesWritten += uint64(len(message))
+	results.CompressedBytes += uint64(compressedSize)
+
 	return nil
 }
 
+// writeCompressed writes the message to dst as a single gzip member, or as a zlib
+// stream when a preset dictionary is set, using a pooled writer, and returns the
+// number of compressed bytes written.
+func (w *Writer) writeCompressed(dst io.Writer, message string) (int, error) {
+	counter := &countingWriter{writer: dst}
+
+	var compressor io.WriteCloser
+	if w.dictPool != nil {
+		zlibWriter := w.dictPool.Get().(*zlib.Writer)
+		defer w.dictPool.Put(zlibWriter)
+		zlibWriter.Reset(counter)
+		compressor = zlibWriter
+	} else {
+		gzipWriter := gzipWriterPool.Get().(*gzip.Writer)
+		defer gzipWriterPool.Put(gzipWriter)
+		gzipWriter.Reset(counter)
+		compressor = gzipWriter
+	}
+
+	if _, err := io.WriteString(compressor, message); err != nil {
+		return counter.count, err
+	}
+	if err := compressor.Close(); err != nil {
+		return counter.count, err
+	}
+	return counter.count, nil
+}
+
+// countingWriter counts the bytes written through it
+type countingWriter struct {
+	writer io.Writer
+	count  int
+}
+
+// Write writes p to the underlying writer and counts the written bytes.
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.writer.Write(p)
+	c.count += n
+	return n, err
+}
+
 // ----------------------------------------------------
 // Mode Methods
 // ----------------------------------------------------
@@ -695,28 +1533,105 @@ func NewResults() *Results {
 		SuccessRate: 0,
 		FailureRate: 0,
 		Info:        make(map[string]interface{}),
+		fileErrs:    make(map[string]error),
 		mu:          sync.RWMutex{},
 	}
 }
 
-// Print prints out the Results struct fields in a human-readable format.
-// It is thread-safe.
-func (r *Results) Print() {
+// CompressionRatio returns the ratio of compressed to uncompressed bytes written,
+// e.g. 0.25 when the files received a quarter of the original size. It returns 0
+// when nothing was written. It is thread-safe.
+func (r *Results) CompressionRatio() float64 {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if r.BytesWritten == 0 {
+		return 0
+	}
+	return float64(r.CompressedBytes) / float64(r.BytesWritten)
+}
+
+// ErrorFor returns the error recorded for the given file name, or nil if the file
+// was written successfully or is not part of the results. It is thread-safe.
+func (r *Results) ErrorFor(fileName string) error {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.fileErrs[fileName]
+}
+
+// addFailure records a failed write for the given file name, appending the error
+// to ErrSlice and increasing the failure count. It is thread-safe.
+func (r *Results) addFailure(fileName string, err error) {
 	r.mu.Lock()
 	defer r.mu.Unlock()
-	fmt.Printf("Total: %d\n", r.Total)
-	fmt.Printf("Success: %d\n", r.Success)
-	fmt.Printf("Failure: %d\n", r.Failure)
-	fmt.Printf("Success Rate: %f\n", r.SuccessRate)
-	fmt.Printf("Failure Rate: %f\n", r.FailureRate)
-	fmt.Print("Info:\n")
-	for key, value := range r.Info {
-		fmt.Printf("%s: %v\n", key, value)
+	r.ErrSlice = append(r.ErrSlice, &err)
+	r.Failure++
+	if r.fileErrs == nil {
+		r.fileErrs = make(map[string]error)
 	}
+	r.fileErrs[fileName] = err
 }
 
-// GetStringRepresentation returns a string that contains a formatted representation
-// of the Results struct fields, including total counts, success and failure metrics,
+// merge adds the counts, errors and Info of from to r and recalculates the rates from
+// the totals.
+func (r *Results) merge(from *Results) {
+	from.mu.RLock()
+	defer from.mu.RUnlock()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.Total += from.Total
+	r.ErrSlice = append(r.ErrSlice, from.ErrSlice...)
+	r.Success += from.Success
+	r.Failure += from.Failure
+	r.BytesWritten += from.BytesWritten
+	r.Deduped += from.Deduped
+	r.CompressedBytes += from.CompressedBytes
+	if r.Info == nil {
+		r.Info = make(map[string]interface{})
+	}
+	for key, value := range from.Info {
+		r.Info[key] = value
+	}
+	if r.fileErrs == nil {
+		r.fileErrs = make(map[string]error)
+	}
+	for fileName, err := range from.fileErrs {
+		r.fileErrs[fileName] = err
+	}
+
+	if r.Total > 0 {
+		r.SuccessRate = float64(r.Success) / float64(r.Total)
+		r.FailureRate = float64(r.Failure) / float64(r.Total)
+	}
+}
+
+// Print prints out the Results struct fields in a human-readable format.
+// When color output is enabled (see SetColorOutput), success lines are printed in
+// green and, if any write failed, failure lines in red. It is thread-safe.
+func (r *Results) Print() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	green, red, reset, redReset := "", "", "", ""
+	if colorOutput {
+		green, reset = colorGreen, colorReset
+		// Only flag failures that happened
+		if r.Failure > 0 {
+			red, redReset = colorRed, colorReset
+		}
+	}
+	fmt.Printf("Total: %d\n", r.Total)
+	fmt.Printf("%sSuccess: %d%s\n", green, r.Success, reset)
+	fmt.Printf("%sFailure: %d%s\n", red, r.Failure, redReset)
+	fmt.Printf("%sSuccess Rate: %f%s\n", green, r.SuccessRate, reset)
+	fmt.Printf("%sFailure Rate: %f%s\n", red, r.FailureRate, redReset)
+	fmt.Print("Info:\n")
+	for key, value := range r.Info {
+		fmt.Printf("%s: %v\n", key, value)
+	}
+}
+
+// GetStringRepresentation returns a string that contains a formatted representation
+// of the Results struct fields, including total counts, success and failure metrics,
 // and any additional information stored in the Info map. The method is thread-safe
 // and locks the mutex to ensure the integrity of the data when accessed concurrently.
 func (r *Results) GetStringRepresentation() string {
@@ -803,6 +1718,7 @@ func (w *Writer) GetConn(file *os.File) (*os.File, error) {
 		// Verify file is still usable
 		if _, err := fileObj.Stat(); err == nil {
 			w.connLastUsed.Store(fileName, time.Now())
+			atomic.AddUint64(&w.poolHits, 1)
 			return fileObj, nil
 		} else {
 			// File is not usable, remove it from pool
@@ -812,6 +1728,8 @@ func (w *Writer) GetConn(file *os.File) (*os.File, error) {
 		}
 	}
 
+	atomic.AddUint64(&w.poolMisses, 1)
+
 	// Find least recently used connection if pool is full
 	var count int
 	w.openFilesPool.Range(func(key, value interface{}) bool {
@@ -839,6 +1757,7 @@ func (w *Writer) GetConn(file *os.File) (*os.File, error) {
 				oldFile.(*os.File).Close()
 				w.openFilesPool.Delete(oldestFile)
 				w.connLastUsed.Delete(oldestFile)
+				atomic.AddUint64(&w.poolEvictions, 1)
 			}
 		}
 	}
@@ -894,6 +1813,65 @@ func (w *Writer) CheckConnStatus(file *os.File) bool {
 	return false
 }
 
+// PoolStats returns the connection pool counters accumulated by GetConn over the
+// Writer's lifetime, which help tune maxPool: a low hit ratio means connections
+// are evicted and reopened between writes.
+func (w *Writer) PoolStats() PoolStats {
+	return PoolStats{
+		Hits:      atomic.LoadUint64(&w.poolHits),
+		Misses:    atomic.LoadUint64(&w.poolMisses),
+		Evictions: atomic.LoadUint64(&w.poolEvictions),
+	}
+}
+
+// Prewarm opens the named files with the Writer's mode flags and stores them in the
+// pool ahead of a write, so the first write to these files finds an open connection
+// instead of paying the open latency. Files already pooled and usable are left as is,
+// and the pool size limit applies as in GetConn. It is meant for 'a' mode; in 'w'
+// mode the files are truncated when opened here. Files that cannot be opened are
+// reported in the returned error, the others are still pooled.
+func (w *Writer) Prewarm(fileNames ...string) error {
+	w.opLock.Lock()
+	defer w.opLock.Unlock()
+
+	err := w.fullWriteCheck()
+	if err != nil {
+		return err
+	}
+
+	fileMode, err := getFileMode(*w.mode.mode)
+	if err != nil {
+		return err
+	}
+
+	var errSlice []error
+	for _, fileName := range fileNames {
+		if pooled, ok := w.openFilesPool.Load(fileName); ok {
+			if _, err := pooled.(*os.File).Stat(); err == nil {
+				Debug("File %s already pooled", fileName)
+				continue
+			}
+		}
+
+		file, err := w.openFile(fileName, fileMode)
+		if err != nil {
+			errSlice = append(errSlice, fmt.Errorf("error opening file %s: %v", fileName, err))
+			continue
+		}
+		if _, err := w.GetConn(file); err != nil {
+			file.Close()
+			errSlice = append(errSlice, err)
+			continue
+		}
+		Debug("File %s prewarmed", fileName)
+	}
+
+	if len(errSlice) > 0 {
+		return fmt.Errorf("multiple errors prewarming files: %v", errSlice)
+	}
+	return nil
+}
+
 // CloseConn closes a file if it is present in the openFilesPool and removes it
 // from the pool. It logs and returns an error if the file cannot be closed or
 // if the file is not found in the pool. Upon successful closure, the file is
@@ -941,7 +1919,15 @@ func (w *Writer) CloseConn(file *os.File) error {
 // CloseAllConns closes all files in the openFilesPool and removes them from the
 // pool. If any of the files cannot be closed, it logs and returns an error. If
 // all files are closed successfully, it returns nil.
+// It waits for any in-flight write operation to finish.
 func (w *Writer) CloseAllConns() error {
+	w.opLock.Lock()
+	defer w.opLock.Unlock()
+	return w.closeAllConns()
+}
+
+// closeAllConns holds the logic of CloseAllConns. The caller must hold opLock.
+func (w *Writer) closeAllConns() error {
 	var errSlice []error
 
 	// Create a copy of the pool to avoid modification during iteration
@@ -989,10 +1975,64 @@ func (w *Writer) CloseAllConns() error {
 	return nil
 }
 
+// Checkpoint establishes a durability point without closing connections. It waits
+// for any running write operation, whose buffered writers are flushed when it ends,
+// and then fsyncs every pooled file unless disabled with SetCheckpointSync. The
+// connections stay open for continued writing.
+func (w *Writer) Checkpoint() error {
+	w.opLock.Lock()
+	defer w.opLock.Unlock()
+
+	if !w.durable {
+		return nil
+	}
+
+	var errSlice []error
+
+	// Create a copy of the pool to avoid modification during iteration
+	filesToSync := make(map[string]*os.File)
+	w.mu.RLock()
+	w.openFilesPool.Range(func(key, value interface{}) bool {
+		filesToSync[key.(string)] = value.(*os.File)
+		return true
+	})
+	w.mu.RUnlock()
+
+	for name, file := range filesToSync {
+		if err := file.Sync(); err != nil {
+			Debug("Error syncing file %s: %v", name, err)
+			errSlice = append(errSlice, fmt.Errorf("error syncing file %s: %v", name, err))
+		}
+	}
+
+	if len(errSlice) > 0 {
+		return fmt.Errorf("multiple errors syncing files: %v", errSlice)
+	}
+	return nil
+}
+
+// SetCheckpointSync enables or disables fsyncing pooled files on Checkpoint. It is
+// enabled by default; when disabled, Checkpoint only waits for buffered data to be
+// flushed to the operating system.
+func (w *Writer) SetCheckpointSync(enabled bool) {
+	w.opLock.Lock()
+	defer w.opLock.Unlock()
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.durable = enabled
+}
+
 // ClearAll clears all the file connections in the openFilesPool and the last used
 // file connections in connLastUsed. It is used to clear the file connections after
 // writing to all files.
 func (w *Writer) ClearAll() {
+	w.opLock.Lock()
+	defer w.opLock.Unlock()
+	w.clearAll()
+}
+
+// clearAll holds the logic of ClearAll. The caller must hold opLock.
+func (w *Writer) clearAll() {
 	w.mu.Lock()
 	w.openFilesPool = sync.Map{}
 	w.connLastUsed = sync.Map{}
@@ -1003,9 +2043,17 @@ func (w *Writer) ClearAll() {
 // setting it to a new empty slice. It is used to clear the files slice after
 // writing to all files.
 func (w *Writer) ClearFiles() {
+	w.opLock.Lock()
+	defer w.opLock.Unlock()
+	w.clearFiles()
+}
+
+// clearFiles holds the logic of ClearFiles. The caller must hold opLock.
+func (w *Writer) clearFiles() {
 	w.mu.Lock()
 	emptySlice := make([]*os.File, 0)
 	w.files = &emptySlice
+	w.openErrs = nil
 	w.mu.Unlock()
 }
 
@@ -1013,13 +2061,19 @@ func (w *Writer) ClearFiles() {
 // file connections, and the files slice. It is used to reset the Writer to its
 // initial state after writing to all files. It returns an error if closing the
 // open file connections fails.
+//
+// FactoryReset waits for any in-flight write operation to finish, so it never
+// clears the files or the pool underneath a running Write.
 func (w *Writer) FactoryReset() error {
-	err := w.CloseAllConns()
+	w.opLock.Lock()
+	defer w.opLock.Unlock()
+
+	err := w.closeAllConns()
 	if err != nil {
 		return err
 	}
-	w.ClearAll()
-	w.ClearFiles()
+	w.clearAll()
+	w.clearFiles()
 	return nil
 }
 
@@ -1031,8 +2085,9 @@ func (w *Writer) FactoryReset() error {
 // retry count is exhausted.
 //
 // Parameters:
-//   - function: The function to be executed, which takes an os.File, a string message,
-//     a Results struct, and a RWMutex as arguments and returns an error.
+//   - ctx: The context of the running write; retrying stops once it is done.
+//   - function: The function to be executed, which takes a context, an os.File, a string
+//     message, a Results struct, and a RWMutex as arguments and returns an error.
 //   - file: The os.File instance to be passed to the function.
 //   - message: The message string to be passed to the function.
 //   - results: A pointer to a Results struct to track outcomes of the function execution.
@@ -1042,7 +2097,8 @@ func (w *Writer) FactoryReset() error {
 //   - An error if the function execution fails after exhausting all retries, or nil if
 //     the function succeeds.
 func (w *Writer) retry(
-	function func(*os.File, string, *Results, *sync.RWMutex) error,
+	ctx context.Context,
+	function func(context.Context, *os.File, string, *Results, *sync.RWMutex) error,
 	file *os.File,
 	message string,
 	results *Results,
@@ -1054,15 +2110,16 @@ func (w *Writer) retry(
 
 	if tries == 0 {
 		// Do func without retry
-		err := function(file, message, results, mu)
+		err := function(ctx, file, message, results, mu)
 		if err != nil {
 			return err
 		}
 		return nil
 	}
 
+	start := time.Now()
 	for i := tries; i > 0; i-- {
-		err := function(file, message, results, mu)
+		err := function(ctx, file, message, results, mu)
 		if err == nil {
 			return nil
 		}
@@ -1070,8 +2127,21 @@ func (w *Writer) retry(
 		if i == 1 { // Last retry
 			return fmt.Errorf("exhausted retries: last error: %v", err)
 		}
+		// Do not retry once the context is done
+		if ctx.Err() != nil {
+			return err
+		}
+		// Do not retry once the retry budget is spent -> the backoff is cut to fit it
+		sleep := time.Duration(backoff) * time.Millisecond
+		if w.retryBudget > 0 {
+			remaining := w.retryBudget - time.Since(start)
+			if remaining <= 0 {
+				return fmt.Errorf("exceeded retry duration of %v: last error: %v", w.retryBudget, err)
+			}
+			sleep = min(sleep, remaining)
+		}
 		Debug("Retrying... %d tries left", i-1)
-		time.Sleep(time.Duration(backoff) * time.Millisecond)
+		time.Sleep(sleep)
 
 		if backoff < 1000 {
 			backoff *= 2
@@ -1089,6 +2159,7 @@ func (w *Writer) retry(
 // the specified maximum number of workers. If maxWorkers is 0 or negative, the
 // number of workers is set to the number of CPUs available. If maxWorkers is greater
 // than the number of files, the number of workers is capped at the number of files.
+// The number of workers is also capped at a multiple of GOMAXPROCS, see SetWorkerCapFactor.
 //
 // The function returns a Results struct containing the total number of files, the
 // number of successful writes, the number of failed writes, the success rate, and
@@ -1103,78 +2174,284 @@ func (w *Writer) retry(
 // the Writer's retries and backoff fields, respectively. If all retries are exhausted
 // without success, the error is returned.
 //
+// If the Writer's context is canceled or reaches its deadline, a *CancelError is
+// returned. When files were already dispatched, the partial Results are returned
+// along with it, and the error holds the number of files completed before.
+//
+// Write operations on the same Writer are serialized, so a Writer (such as Dwriter)
+// can be shared between goroutines as a common sink.
+//
 // If the length of the files slices is greater than 1000, the function splits the
 // files into chunks, calculated as the length of the file slice divided by the number
 // of cpus, and writes them in parallel.
 func (w *Writer) Write(maxWorkers int) (*Results, error) {
-	// Check Context
-	select {
-	case <-w.ctx.Done():
-		return nil, w.ctx.Err()
-	default:
+	w.opLock.Lock()
+	defer w.opLock.Unlock()
+
+	if err := w.fullWriteCheck(); err != nil {
+		return nil, err
 	}
+	return w.write(w.ctx, maxWorkers, *w.message)
+}
+
+// writeWithContext writes the message to each file like Write, but under the given
+// context instead of the Writer's context. It is the entry point of asynchronous
+// writes, which own their context.
+func (w *Writer) writeWithContext(ctx context.Context, maxWorkers int) (*Results, error) {
+	w.opLock.Lock()
+	defer w.opLock.Unlock()
 
 	if err := w.fullWriteCheck(); err != nil {
 		return nil, err
 	}
-	if len(*w.files) == 0 {
-		return nil, fmt.Errorf("files is empty")
+	return w.write(ctx, maxWorkers, *w.message)
+}
+
+// WriteMessage writes the given message to each file like Write, without changing
+// the Writer's message. Since SetMessage followed by Write is not atomic, goroutines
+// sharing a Writer (such as Dwriter) should use WriteMessage to write their own
+// messages.
+func (w *Writer) WriteMessage(message string, maxWorkers int) (*Results, error) {
+	w.opLock.Lock()
+	defer w.opLock.Unlock()
+
+	if err := w.fullWriteCheck(); err != nil {
+		return nil, err
 	}
+	return w.write(w.ctx, maxWorkers, message)
+}
+
+// WriteInto writes the message to each file like Write, but adds the outcome to the
+// given Results instead of returning a new one. Counts, errors and Info accumulate
+// across calls and the rates are recalculated from the totals, which allows
+// aggregating several writes (e.g. to different file sets) into one Results.
+func (w *Writer) WriteInto(r *Results, maxWorkers int) error {
+	w.opLock.Lock()
+	defer w.opLock.Unlock()
+
+	if r == nil {
+		return fmt.Errorf("results is nil")
+	}
+	if err := w.fullWriteCheck(); err != nil {
+		return err
+	}
+	message := *w.message
 
-	// Initialize results
+	// Write into per-write results -> reported on their own, then added to r
 	results := NewResults()
+	err := w.writeInto(w.ctx, results, maxWorkers, func(fileName string) (string, error) {
+		return message, nil
+	})
+	r.merge(results)
+	return err
+}
 
-	// Initialize Worker Count
-	if maxWorkers <= 0 {
-		maxWorkers = runtime.NumCPU()
+// write runs the worker pool that writes the given message to each file in the
+// files slice, under the given context. The caller must hold opLock.
+func (w *Writer) write(ctx context.Context, maxWorkers int, message string) (*Results, error) {
+	return w.writeEach(ctx, maxWorkers, func(fileName string) (string, error) {
+		return message, nil
+	})
+}
+
+// writeEach runs the worker pool that writes to each file in the files slice the
+// message returned by messageFor for that file. A messageFor error marks the file
+// as failed without writing it. It holds the shared logic for Write and the
+// formatted write methods. The caller must hold opLock.
+func (w *Writer) writeEach(ctx context.Context, maxWorkers int, messageFor func(fileName string) (string, error)) (*Results, error) {
+	results := NewResults()
+	if err := w.writeInto(ctx, results, maxWorkers, messageFor); err != nil {
+		// Keep the partial results of a write interrupted mid-batch
+		if cancelErr, ok := err.(*CancelError); ok && cancelErr.Started {
+			return results, err
+		}
+		return nil, err
 	}
+	return results, nil
+}
 
-	// Cap Worker Count
-	if maxWorkers > len(*w.files) {
-		maxWorkers = len(*w.files)
+// writeInto holds the logic of writeEach, adding the outcome of the write to the
+// given results rather than to a new Results. The write runs under a cancelable
+// context derived from parent, which is passed down to the workers instead of being
+// stored on the Writer. The caller must hold opLock.
+func (w *Writer) writeInto(parent context.Context, results *Results, maxWorkers int, messageFor func(fileName string) (string, error)) error {
+	if err := w.fullWriteCheck(); err != nil {
+		return err
+	}
+
+	// Check Context
+	select {
+	case <-parent.Done():
+		err := &CancelError{Total: len(*w.files), Err: parent.Err()}
+		w.reportError(err)
+		return err
+	default:
+	}
+
+	// Paths that failed to open are still reported when no file could be opened
+	if len(*w.files) == 0 && len(w.openErrs) == 0 {
+		err := fmt.Errorf("files is empty")
+		w.reportError(err)
+		return err
+	}
+	if err := checkDuplicateNames(*w.files); err != nil {
+		w.reportError(err)
+		return err
+	}
+
+	// Derive a cancelable context for this write -> used by Cancel
+	ctx, done := w.beginCancelable(parent)
+	defer done()
+
+	// Prepare results -> may hold the totals of earlier writes
+	results.mu.Lock()
+	if results.Info == nil {
+		results.Info = make(map[string]interface{})
+	}
+	if results.fileErrs == nil {
+		results.fileErrs = make(map[string]error)
+	}
+	results.mu.Unlock()
+
+	// Progress tracking -> paths that failed to open count as targets
+	total := len(*w.files) + len(w.openErrs)
+	var completed int64
+	var written int64 // Successful files, reported on cancellation
+
+	// Record paths that failed to open as pre-failures
+	for path, openErr := range w.openErrs {
+		results.addFailure(path, openErr)
+		results.mu.Lock()
+		results.Info[path] = openErr.Error()
+		results.mu.Unlock()
+		w.sendProgress(ctx, path, int(atomic.AddInt64(&completed, 1)), total, openErr)
 	}
 
+	// Initialize Worker Count
+	maxWorkers = w.workerCount(maxWorkers, len(*w.files))
+
 	// Initialize wait group
 	wg := sync.WaitGroup{}
 
+	// Adaptive worker scaling
+	var scaler *workerScaler
+	if w.adaptive {
+		scaler = w.newWorkerScaler(maxWorkers)
+		maxWorkers = scaler.max
+	} else {
+		atomic.StoreInt64(&w.activeWorkers, int64(maxWorkers))
+	}
+	defer atomic.StoreInt64(&w.activeWorkers, 0)
+
+	// Forget writes outside the dedup window
+	if w.dedupTTL > 0 {
+		w.pruneRecentWrites()
+	}
+
 	// Create jobs channel
 	jobs := make(chan *os.File, len(*w.files))
 
 	// Start worker pool
 	for i := 0; i < maxWorkers; i++ {
 		wg.Add(1)
-		go func() {
+		go func(id int) {
 			defer wg.Done()
-			for file := range jobs {
+			for {
+				// Wait until this worker is allowed to take jobs
+				if scaler != nil && !scaler.wait(id, &completed, total) {
+					return
+				}
+				file, ok := <-jobs
+				if !ok {
+					return
+				}
+				start := time.Now()
+				fileName := "nil_file"
+				if file != nil {
+					fileName = file.Name()
+				}
+
 				// Get Connection
 				file, errConn := w.GetConn(file)
 				if errConn != nil {
-					errCopy := errConn
-					results.mu.Lock()
-					results.ErrSlice = append(results.ErrSlice, &errCopy)
-					results.Failure++
-					results.mu.Unlock()
+					results.addFailure(fileName, errConn)
+					if scaler != nil {
+						scaler.record(time.Since(start), errConn)
+					}
+					w.sendProgress(ctx, fileName, int(atomic.AddInt64(&completed, 1)), total, errConn)
 					continue
 				}
-				// Retry Wrapper
-				err := w.retry(w.writeToFile, file, *w.message, results, &results.mu)
+				// Build message for this file, then Retry Wrapper
+				message, err := messageFor(fileName)
+				if err == nil && w.checksum {
+					message = w.withChecksumFooter(message)
+				}
+				// Skip identical writes within the dedup window
+				var dedupKey [32]byte
+				if err == nil && w.dedupTTL > 0 {
+					dedupKey = writeHash(fileName, message)
+					if w.isRecentWrite(dedupKey) {
+						Debug("File %s written with the same message recently, skipping write", fileName)
+						results.mu.Lock()
+						results.Success++
+						results.Deduped++
+						atomic.AddInt64(&written, 1)
+						results.Info[fileName] = "deduped"
+						results.mu.Unlock()
+						if scaler != nil {
+							scaler.record(time.Since(start), nil)
+						}
+						w.sendProgress(ctx, fileName, int(atomic.AddInt64(&completed, 1)), total, nil)
+						continue
+					}
+				}
+				// Record the intended write in the WAL
+				var walEntry string
+				if err == nil && w.walDir != "" {
+					walEntry, err = w.walRecord(fileName, message)
+				}
+				if err == nil {
+					err = w.retry(ctx, w.writeToFile, file, message, results, &results.mu)
+				}
+				// Mark the WAL entry as applied
+				if err == nil && walEntry != "" {
+					w.walApplied(walEntry)
+				}
+				// After write hook
+				if err == nil && w.afterWrite != nil {
+					if hookErr := w.afterWrite(fileName, results); hookErr != nil {
+						err = fmt.Errorf("after write hook failed for file %s: %v", fileName, hookErr)
+						results.mu.Lock()
+						results.Info[fileName] = err.Error()
+						results.mu.Unlock()
+					}
+				}
 				if err != nil {
-					errCopy := err
-					results.mu.Lock()
-					results.ErrSlice = append(results.ErrSlice, &errCopy)
-					results.Failure++
-					results.mu.Unlock()
+					results.addFailure(fileName, err)
 				} else {
+					if w.dedupTTL > 0 {
+						w.markRecentWrite(dedupKey)
+					}
 					results.mu.Lock()
 					results.Success++
 					results.mu.Unlock()
+					atomic.AddInt64(&written, 1)
 				}
+				if scaler != nil {
+					scaler.record(time.Since(start), err)
+				}
+				w.sendProgress(ctx, fileName, int(atomic.AddInt64(&completed, 1)), total, err)
 			}
-		}()
+		}(i)
 	}
 
-	// Determine if batching is needed
-	if len(*w.files) > 1000 {
+	// Determine dispatch order and if batching is needed
+	if w.sortBySize {
+		// Process largest files first
+		for _, file := range sortFilesBySize(*w.files) {
+			jobs <- file
+		}
+	} else if len(*w.files) > 1000 {
 		// Process in batches
 		batches := w.batcher(0)
 		for _, batch := range batches {
@@ -1195,12 +2472,22 @@ func (w *Writer) Write(maxWorkers int) (*Results, error) {
 	// Wait for all workers to finish
 	wg.Wait()
 
+	// Report the outcome once the results are final and unlocked
+	defer w.report(results)
+
 	// Calculate final results
 	results.mu.Lock()
 	defer results.mu.Unlock()
 
-	// Set total
-	results.Total = uint64(len(*w.files))
+	// Report a cancellation that interrupted the write
+	var cancelErr error
+	if ctxErr := ctx.Err(); ctxErr != nil && int(written) < len(*w.files) {
+		cancelErr = &CancelError{Started: true, Completed: int(written), Total: len(*w.files), Err: ctxErr}
+		results.Info["canceled"] = cancelErr.Error()
+	}
+
+	// Add to total
+	results.Total += uint64(total)
 
 	// Calculate rates
 	if results.Total > 0 {
@@ -1211,14 +2498,98 @@ func (w *Writer) Write(maxWorkers int) (*Results, error) {
 		results.FailureRate = 0.0
 	}
 
-	return results, nil
+	return cancelErr
+}
+
+// report passes the final results of a write operation to the report func, if set.
+func (w *Writer) report(results *Results) {
+	if w.reportFunc != nil {
+		w.reportFunc(results)
+	}
+}
+
+// reportError passes to the report func, if set, the Results of a write operation
+// that failed before writing any file, with every file recorded as failed with err.
+func (w *Writer) reportError(err error) {
+	if w.reportFunc == nil {
+		return
+	}
+	results := NewResults()
+	results.Total = uint64(len(*w.files))
+	for _, file := range *w.files {
+		fileName := "nil_file"
+		if file != nil {
+			fileName = file.Name()
+		}
+		results.addFailure(fileName, err)
+	}
+	if results.Total > 0 {
+		results.FailureRate = 1.0
+	} else {
+		results.ErrSlice = append(results.ErrSlice, &err)
+	}
+	results.Info["error"] = err.Error()
+	w.report(results)
+}
+
+// beginCancelable derives the cancelable context of a write from parent and registers
+// its cancel func, so Cancel can abort the write. The returned func unregisters and
+// releases the context once the write is done. The caller must hold opLock.
+func (w *Writer) beginCancelable(parent context.Context) (context.Context, func()) {
+	ctx, cancel := context.WithCancel(parent)
+	w.cancelLock.Lock()
+	w.cancel = cancel
+	w.cancelLock.Unlock()
+	return ctx, func() {
+		w.cancelLock.Lock()
+		w.cancel = nil
+		w.cancelLock.Unlock()
+		cancel()
+	}
+}
+
+// workerCount returns the number of workers used to write to the given number of
+// files: maxWorkers, or the number of CPUs if it is 0 or negative, capped at the
+// number of files and at the worker cap factor times GOMAXPROCS.
+func (w *Writer) workerCount(maxWorkers int, files int) int {
+	if maxWorkers <= 0 {
+		maxWorkers = runtime.NumCPU()
+	}
+
+	// Cap Worker Count
+	if maxWorkers > files {
+		maxWorkers = files
+	}
+	if w.workerFactor > 0 {
+		workerCap := runtime.GOMAXPROCS(0) * w.workerFactor
+		if maxWorkers > workerCap {
+			Debug("Capping workers from %d to %d", maxWorkers, workerCap)
+			maxWorkers = workerCap
+		}
+	}
+	return maxWorkers
+}
+
+// sendProgress sends a Progress event on the progress channel, if one is set.
+// It gives up when the write's context is done so a canceled write never blocks
+// on a consumer that stopped draining.
+func (w *Writer) sendProgress(ctx context.Context, fileName string, completed int, total int, err error) {
+	if w.progressCh == nil {
+		return
+	}
+	event := Progress{FileName: fileName, Completed: completed, Total: total, Err: err}
+	select {
+	case w.progressCh <- event:
+	case <-ctx.Done():
+	}
 }
 
 // WriteWithTimeout writes the message to each file in the files slice with a specified timeout.
 //
-// This function creates a context with a timeout and uses it to control the execution
-// of the Write method. If the timeout is reached before the write operation completes,
-// the context is canceled, terminating the operation.
+// This function derives a context with a timeout from the Writer's context and uses it
+// to control this write only. If the timeout is reached before the write operation
+// completes, the context is canceled, terminating the operation. The Writer's context
+// is left unchanged.
 //
 // Parameters:
 //   - maxWorkers: The maximum number of concurrent workers to use for writing.
@@ -1229,15 +2600,18 @@ func (w *Writer) Write(maxWorkers int) (*Results, error) {
 //     number of files processed, the number of successful writes, and the success rate.
 //   - An error if the write operation fails, or if the context is canceled due to the timeout.
 func (w *Writer) WriteWithTimeout(maxWorkers int, timeout time.Duration) (*Results, error) {
+	w.opLock.Lock()
+	defer w.opLock.Unlock()
+
+	if err := w.fullWriteCheck(); err != nil {
+		return nil, err
+	}
+
 	// Create context with timeout
-	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	ctx, cancel := context.WithTimeout(w.ctx, timeout)
 	defer cancel()
 
-	// Update the writer's context
-	w.SetContext(ctx)
-
-	// Call the regular Write method
-	return w.Write(maxWorkers)
+	return w.write(ctx, maxWorkers, *w.message)
 }
 
 // StartWriteWithCancel starts a goroutine to write to all files in the writer with
@@ -1247,15 +2621,16 @@ func (w *Writer) WriteWithTimeout(maxWorkers int, timeout time.Duration) (*Resul
 // write operation when it completes. The error channel will receive an error if
 // the write operation fails. If the context is canceled, the write operation will
 // be terminated and an error will be sent on the error channel.
+//
+// The cancelable context belongs to this write only; the Writer's context is left
+// unchanged, so cancel never affects other writes on the same Writer.
 func StartWriteWithCancel(w *Writer, maxWorkers int) (cancel func(), resultCh chan *Results, errCh chan error) {
 	ctx, cancel := context.WithCancel(context.Background())
 	resultCh = make(chan *Results, 1)
 	errCh = make(chan error, 1)
 
-	w.SetContext(ctx)
-
 	go func() {
-		results, err := w.Write(maxWorkers)
+		results, err := w.writeWithContext(ctx, maxWorkers)
 		if err != nil {
 			errCh <- err
 			return
@@ -1266,10 +2641,459 @@ func StartWriteWithCancel(w *Writer, maxWorkers int) (cancel func(), resultCh ch
 	return cancel, resultCh, errCh
 }
 
+// WriteLogfmt formats the given fields as a single logfmt line and writes it to each
+// file in the files slice, using the same worker pool, retry and mode settings as Write.
+// In 'a' mode every call appends one line to each file, terminated by the Writer's
+// newline style.
+//
+// Keys are written in sorted order so the output is deterministic. Values that are
+// empty or contain spaces, quotes, '=' or control characters are quoted.
+//
+// Example output:
+//
+//	level=info msg="user logged in" user=jr
+//
+// An error is returned, before anything is written, if any key is empty or contains
+// spaces, quotes or '='.
+func (w *Writer) WriteLogfmt(fields map[string]string, maxWorkers int) (*Results, error) {
+	line, err := formatLogfmt(fields)
+	if err != nil {
+		return nil, err
+	}
+
+	w.opLock.Lock()
+	defer w.opLock.Unlock()
+	return w.write(w.ctx, maxWorkers, line+w.newline.String())
+}
+
+// WriteJSON marshals obj to JSON, indented according to SetJSONIndent, and writes the
+// same document to each file in the files slice, using the same worker pool, retry
+// and mode settings as Write. It is useful to replicate a JSON document, such as a
+// config file, to multiple locations.
+//
+// If obj cannot be marshaled, the error is returned before anything is written.
+func (w *Writer) WriteJSON(obj interface{}, maxWorkers int) (*Results, error) {
+	w.opLock.Lock()
+	defer w.opLock.Unlock()
+
+	var data []byte
+	var err error
+	if w.jsonIndent != "" {
+		data, err = json.MarshalIndent(obj, "", w.jsonIndent)
+	} else {
+		data, err = json.Marshal(obj)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal JSON: %v", err)
+	}
+
+	return w.write(w.ctx, maxWorkers, string(data))
+}
+
+// WriteLineDelta appends to each file only the given lines that are not already
+// present in it. The existing content of each file is read and split into lines, and
+// the new lines are computed as an order preserving set difference, so re-running the
+// same write does not duplicate entries. Lines are terminated by the Writer's newline
+// style. It requires 'a' mode; files with nothing new are left unchanged.
+func (w *Writer) WriteLineDelta(lines []string, maxWorkers int) (*Results, error) {
+	w.opLock.Lock()
+	defer w.opLock.Unlock()
+
+	if err := w.fullWriteCheck(); err != nil {
+		return nil, err
+	}
+	if *w.mode.mode != "a" {
+		return nil, fmt.Errorf("line delta writes require 'a' mode, got: %s", *w.mode.mode)
+	}
+
+	return w.writeEach(w.ctx, maxWorkers, func(fileName string) (string, error) {
+		return w.lineDelta(fileName, lines)
+	})
+}
+
+// lineDelta returns the lines not already present in the file, joined and terminated
+// by the Writer's newline style. A file that does not exist has no lines.
+func (w *Writer) lineDelta(fileName string, lines []string) (string, error) {
+	content, err := os.ReadFile(fileName)
+	if err != nil && !os.IsNotExist(err) {
+		return "", fmt.Errorf("error reading file %s: %v", fileName, err)
+	}
+
+	existing := make(map[string]struct{})
+	for _, line := range strings.Split(string(content), "\n") {
+		existing[strings.TrimSuffix(line, "\r")] = struct{}{}
+	}
+
+	var builder strings.Builder
+	for _, line := range lines {
+		if _, ok := existing[line]; ok {
+			continue
+		}
+		existing[line] = struct{}{}
+		builder.WriteString(line)
+		builder.WriteString(w.newline.String())
+	}
+
+	// Keep the delta on its own lines if the file does not end with a line ending
+	if builder.Len() > 0 && len(content) > 0 && content[len(content)-1] != '\n' {
+		return w.newline.String() + builder.String(), nil
+	}
+	return builder.String(), nil
+}
+
+// formatLogfmt builds a logfmt line, without line ending, from the given fields.
+func formatLogfmt(fields map[string]string) (string, error) {
+	if len(fields) == 0 {
+		return "", fmt.Errorf("fields is empty")
+	}
+
+	keys := make([]string, 0, len(fields))
+	for key := range fields {
+		if key == "" || strings.ContainsAny(key, " =\"\t\r\n") {
+			return "", fmt.Errorf("invalid logfmt key: %q", key)
+		}
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+
+	var builder strings.Builder
+	for i, key := range keys {
+		if i > 0 {
+			builder.WriteByte(' ')
+		}
+		builder.WriteString(key)
+		builder.WriteByte('=')
+		builder.WriteString(logfmtValue(fields[key]))
+	}
+
+	return builder.String(), nil
+}
+
+// logfmtValue quotes a logfmt value when it is empty or contains characters that
+// would break the key=value format.
+func logfmtValue(value string) string {
+	if value == "" || strings.ContainsAny(value, " =\"\\") || strings.IndexFunc(value, unicode.IsControl) >= 0 {
+		return strconv.Quote(value)
+	}
+	return value
+}
+
+// ----------------------------------------------------
+// Adaptive Workers
+// ----------------------------------------------------
+
+// workerScaler adjusts the number of active workers during a write, based on the
+// latency and errors observed over each round of writes.
+type workerScaler struct {
+	w          *Writer       // Writer publishing the active worker count
+	min        int           // Minimum number of active workers
+	max        int           // Maximum number of active workers
+	mu         sync.Mutex    // Mutex for the window stats
+	count      int           // Writes in the current window
+	errs       int           // Errors in the current window
+	latency    time.Duration // Total latency in the current window
+	prevAvg    time.Duration // Average latency of the previous window
+	slowFactor float64       // Latency increase considered a slowdown
+}
+
+// newWorkerScaler creates a workerScaler bounded by the Writer's adaptive bounds,
+// using maxWorkers as the upper bound when no max is configured.
+func (w *Writer) newWorkerScaler(maxWorkers int) *workerScaler {
+	upper := maxWorkers
+	if w.adaptiveMax > 0 && w.adaptiveMax < upper {
+		upper = w.adaptiveMax
+	}
+	lower := w.adaptiveMin
+	if lower < 1 {
+		lower = 1
+	}
+	if lower > upper {
+		lower = upper
+	}
+	atomic.StoreInt64(&w.activeWorkers, int64(lower))
+	return &workerScaler{w: w, min: lower, max: upper, slowFactor: 1.5}
+}
+
+// wait blocks until the worker with the given id is allowed to take jobs. It returns
+// false once all jobs are completed, so gated workers can exit.
+func (s *workerScaler) wait(id int, completed *int64, total int) bool {
+	for int64(id) >= atomic.LoadInt64(&s.w.activeWorkers) {
+		if int(atomic.LoadInt64(completed)) >= total {
+			return false
+		}
+		time.Sleep(time.Millisecond)
+	}
+	return true
+}
+
+// record adds a write outcome to the current window. Once a window holds one write
+// per active worker, the active count is increased if writes were error free and
+// not slower than the previous window, or decreased otherwise.
+func (s *workerScaler) record(latency time.Duration, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.count++
+	s.latency += latency
+	if err != nil {
+		s.errs++
+	}
+
+	active := atomic.LoadInt64(&s.w.activeWorkers)
+	if int64(s.count) < active {
+		return
+	}
+
+	avg := s.latency / time.Duration(s.count)
+	slower := s.prevAvg > 0 && float64(avg) > float64(s.prevAvg)*s.slowFactor
+
+	switch {
+	case (s.errs > 0 || slower) && active > int64(s.min):
+		active--
+		Debug("Adaptive workers: decreasing to %d (errors: %d, avg latency: %v)", active, s.errs, avg)
+	case s.errs == 0 && !slower && active < int64(s.max):
+		active++
+		Debug("Adaptive workers: increasing to %d (avg latency: %v)", active, avg)
+	}
+	atomic.StoreInt64(&s.w.activeWorkers, active)
+
+	s.prevAvg = avg
+	s.count = 0
+	s.errs = 0
+	s.latency = 0
+}
+
+// ----------------------------------------------------
+// Verify Methods
+// ----------------------------------------------------
+
+// writeHash returns the key identifying a write of message to fileName.
+func writeHash(fileName string, message string) [32]byte {
+	hasher := sha256.New()
+	hasher.Write([]byte(fileName))
+	hasher.Write([]byte{0})
+	hasher.Write([]byte(message))
+	var key [32]byte
+	hasher.Sum(key[:0])
+	return key
+}
+
+// isRecentWrite reports whether the write identified by key succeeded within the
+// dedup window.
+func (w *Writer) isRecentWrite(key [32]byte) bool {
+	w.dedupLock.Lock()
+	defer w.dedupLock.Unlock()
+	seenAt, ok := w.dedupSeen[key]
+	return ok && time.Since(seenAt) < w.dedupTTL
+}
+
+// pruneRecentWrites drops the hashes of writes older than the dedup window.
+func (w *Writer) pruneRecentWrites() {
+	w.dedupLock.Lock()
+	defer w.dedupLock.Unlock()
+	for key, seenAt := range w.dedupSeen {
+		if time.Since(seenAt) >= w.dedupTTL {
+			delete(w.dedupSeen, key)
+		}
+	}
+}
+
+// markRecentWrite records the successful write identified by key.
+func (w *Writer) markRecentWrite(key [32]byte) {
+	w.dedupLock.Lock()
+	defer w.dedupLock.Unlock()
+	if w.dedupSeen == nil {
+		w.dedupSeen = make(map[[32]byte]time.Time)
+	}
+	w.dedupSeen[key] = time.Now()
+}
+
+// withChecksumFooter terminates the message with a line ending, if needed, and
+// appends a footer line holding the sha256 checksum of the terminated message.
+// The record is assembled in a pooled buffer to avoid intermediate allocations.
+func (w *Writer) withChecksumFooter(message string) string {
+	newline := w.newline.String()
+
+	bufferPtr := recordBufferPool.Get().(*[]byte)
+	record := append((*bufferPtr)[:0], message...)
+	if message != "" && !strings.HasSuffix(message, "\n") {
+		record = append(record, newline...)
+	}
+
+	sum := sha256.Sum256(record)
+	record = append(record, checksumPrefix...)
+	record = hex.AppendEncode(record, sum[:])
+	record = append(record, newline...)
+
+	result := string(record)
+	*bufferPtr = record
+	recordBufferPool.Put(bufferPtr)
+	return result
+}
+
+// VerifyChecksumFooter checks the checksum footers of the given file. The file is
+// split at each footer line and every record must match the checksum of its footer,
+// so both 'w' mode files and 'a' mode files holding several records are verified.
+//
+// It returns false if any record does not match its footer, and an error if the
+// file cannot be read or has no checksum footer.
+func VerifyChecksumFooter(fileName string) (bool, error) {
+	content, err := os.ReadFile(fileName)
+	if err != nil {
+		return false, fmt.Errorf("error reading file %s: %v", fileName, err)
+	}
+
+	var record []byte
+	footers := 0
+	for _, line := range strings.SplitAfter(string(content), "\n") {
+		trimmed := strings.TrimRight(line, "\r\n")
+		if !strings.HasPrefix(trimmed, checksumPrefix) {
+			record = append(record, line...)
+			continue
+		}
+
+		footers++
+		sum := sha256.Sum256(record)
+		if hex.EncodeToString(sum[:]) != strings.TrimPrefix(trimmed, checksumPrefix) {
+			Debug("Checksum mismatch in file %s at footer %d", fileName, footers)
+			return false, nil
+		}
+		record = record[:0]
+	}
+
+	if footers == 0 {
+		return false, fmt.Errorf("no checksum footer found in file %s", fileName)
+	}
+	// Content after the last footer is not covered by any checksum
+	if len(record) > 0 {
+		Debug("File %s has content after its last checksum footer", fileName)
+		return false, nil
+	}
+	return true, nil
+}
+
+// VerifyAll reopens each file in the files slice read-only and checks that the
+// Writer's message is present. In 'w' mode the file content must match the message
+// exactly, while in 'a' mode the content must end with the message.
+//
+// The function returns a map of file names to verification results. Files that
+// cannot be opened or read are reported as false. An error is returned if the
+// Writer's fullWriteCheck fails or if the files slice is empty.
+func (w *Writer) VerifyAll() (map[string]bool, error) {
+	w.opLock.Lock()
+	defer w.opLock.Unlock()
+
+	if err := w.fullWriteCheck(); err != nil {
+		return nil, err
+	}
+	if len(*w.files) == 0 {
+		return nil, fmt.Errorf("files is empty")
+	}
+
+	verified := make(map[string]bool, len(*w.files))
+	for _, file := range *w.files {
+		if file == nil {
+			continue
+		}
+		fileName := file.Name()
+
+		content, err := os.ReadFile(fileName)
+		if err != nil {
+			Debug("Error reading file %s for verification: %v", fileName, err)
+			verified[fileName] = false
+			continue
+		}
+
+		switch *w.mode.mode {
+		case "w":
+			verified[fileName] = string(content) == *w.message
+		default:
+			verified[fileName] = strings.HasSuffix(string(content), *w.message)
+		}
+	}
+
+	return verified, nil
+}
+
+// TruncationImpact reports, for each file in the files slice, how many existing bytes
+// would be lost by a 'w' mode write, which is the current size of the file. Files
+// that do not exist yet report 0. It is meant to warn before clobbering large files.
+func (w *Writer) TruncationImpact() map[string]int64 {
+	w.opLock.Lock()
+	defer w.opLock.Unlock()
+
+	impact := make(map[string]int64, len(*w.files))
+	for _, file := range *w.files {
+		if file == nil {
+			continue
+		}
+		fileName := file.Name()
+
+		info, err := os.Stat(fileName)
+		if err != nil {
+			Debug("Error getting size of file %s: %v", fileName, err)
+			impact[fileName] = 0
+			continue
+		}
+		impact[fileName] = info.Size()
+	}
+
+	return impact
+}
+
 // ----------------------------------------------------
 // Batcher
 // ----------------------------------------------------
 
+// checkDuplicateNames returns an error naming the conflict if two distinct file
+// handles share the same name, since the connection pool is keyed by name and would
+// confuse them. The same handle listed twice is not a conflict.
+func checkDuplicateNames(files []*os.File) error {
+	seen := make(map[string]*os.File, len(files))
+	for _, file := range files {
+		if file == nil {
+			continue
+		}
+		fileName := file.Name()
+		if existing, ok := seen[fileName]; ok && existing != file {
+			logger.Print("Duplicate file name with different handles: ", fileName)
+			return fmt.Errorf("duplicate file name with different handles: %s", fileName)
+		}
+		seen[fileName] = file
+	}
+	return nil
+}
+
+// sortFilesBySize returns a copy of files ordered by size, largest first. Each file
+// is stat'ed once; nil files and files that cannot be stat'ed go last.
+func sortFilesBySize(files []*os.File) []*os.File {
+	type sizedFile struct {
+		file *os.File
+		size int64
+	}
+
+	sized := make([]sizedFile, len(files))
+	for i, file := range files {
+		sized[i] = sizedFile{file: file, size: -1}
+		if file == nil {
+			continue
+		}
+		if info, err := os.Stat(file.Name()); err == nil {
+			sized[i].size = info.Size()
+		}
+	}
+
+	slices.SortStableFunc(sized, func(a, b sizedFile) int {
+		return cmp.Compare(b.size, a.size)
+	})
+
+	sorted := make([]*os.File, len(sized))
+	for i, entry := range sized {
+		sorted[i] = entry.file
+	}
+	return sorted
+}
+
 // batcher splits the files into batches of size batchSize
 func (w *Writer) batcher(batchSize int) [][]*os.File {
 	if batchSize <= 0 {